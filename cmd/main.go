@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	_ "github.com/IosifSuzuki/todo/docs"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
 	db "github.com/IosifSuzuki/todo/internall/db"
 	"github.com/IosifSuzuki/todo/internall/handler"
 	"github.com/IosifSuzuki/todo/internall/logger"
 	"github.com/IosifSuzuki/todo/internall/middleware"
+	"github.com/IosifSuzuki/todo/internall/storage"
 	"github.com/IosifSuzuki/todo/internall/utility"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -34,12 +37,18 @@ import (
 // @host todo-app
 // @BasePath /api/v1
 func main() {
-	rootRouter := configureRouter()
 	utility.Setup()
-	db.ConnectToDB()
+	store := openStore()
 	defer func() {
-		_ = db.CloseConnectionToDB()
+		_ = store.Close()
 	}()
+	blobs, err := storage.NewFileSystemStore(utility.Config.Attachments.Dir)
+	if err != nil {
+		logger.Fatal("Cannot open attachment storage", zap.Error(err))
+	}
+	go purgeTrash(store, blobs, utility.Config.TrashRetention)
+	go sendReminders(store)
+	rootRouter := configureRouter(store, blobs)
 
 	server := http.Server{
 		Addr:         ":8080",
@@ -53,9 +62,109 @@ func main() {
 	}
 }
 
-func configureRouter() http.Handler {
+func openStore() db.Store {
+	switch utility.Config.Storage {
+	case utility.StorageMemory:
+		logger.Info("Using in-memory storage")
+		return db.NewMemoryStore()
+	case utility.StoragePostgres:
+		return db.ConnectToDB()
+	default:
+		logger.Fatal("Unknown storage backend", zap.String("storage", utility.Config.Storage))
+		return nil
+	}
+}
+
+// trashPurgeInterval is how often todos past the trash retention are removed for good.
+const trashPurgeInterval = time.Hour
+
+func purgeTrash(store db.Store, blobs storage.BlobStore, retention time.Duration) {
+	var ticker = time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		purged, err := store.PurgeTrash(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Cannot purge trash", zap.Error(err))
+			continue
+		}
+		if purged > 0 {
+			logger.Info("Purged todos from trash", zap.Int64("count", purged))
+		}
+		purgeAttachments(store, blobs)
+	}
+}
+
+// purgeAttachments deletes the content of attachments whose todos were purged.
+func purgeAttachments(store db.AttachmentStore, blobs storage.BlobStore) {
+	keys, err := store.PurgeDetachedAttachments(context.Background())
+	if err != nil {
+		logger.Error("Cannot purge attachments", zap.Error(err))
+		return
+	}
+	for _, key := range keys {
+		if err := blobs.Delete(context.Background(), key); err != nil {
+			logger.Error("Cannot delete attachment content", zap.String("key", key), zap.Error(err))
+		}
+	}
+	if len(keys) > 0 {
+		logger.Info("Purged attachments of removed todos", zap.Int("count", len(keys)))
+	}
+}
+
+// reminderInterval is how often todos are checked for reminders which became due.
+const reminderInterval = time.Minute
+
+func sendReminders(store db.NotificationStore) {
+	var ticker = time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		sent, err := store.SendDueReminders(context.Background(), time.Now())
+		if err != nil {
+			logger.Error("Cannot send reminders", zap.Error(err))
+			continue
+		}
+		if sent > 0 {
+			logger.Info("Sent reminder notifications", zap.Int64("count", sent))
+		}
+	}
+}
+
+// changeReplaySize is how many recent todo changes are kept for clients resuming their event stream.
+const changeReplaySize = 1024
+
+func configureRouter(store db.Store, blobs storage.BlobStore) http.Handler {
+	var changes = broadcast.NewHub(changeReplaySize)
 	var amw = middleware.AuthenticationMiddleware{}
 	var lms = middleware.LoggerMiddleware{}
+	var accountHandler = handler.AccountHandler{Accounts: store}
+	var authenticationHandler = handler.AuthenticationHandler{Accounts: store}
+	var todoHandler = handler.TodoHandler{
+		Todos:      store,
+		Accounts:   store,
+		Lists:      store,
+		Tags:       store,
+		Events:     store,
+		UndoWindow: utility.Config.UndoWindow,
+
+		Attachments:       store,
+		Blobs:             blobs,
+		MaxAttachmentSize: utility.Config.Attachments.MaxSize,
+		AttachmentQuota:   utility.Config.Attachments.Quota,
+		Comments:          store,
+		Notifications:     store,
+		Changes:           changes,
+	}
+	var listHandler = handler.ListHandler{Lists: store}
+	var tagHandler = handler.TagHandler{Tags: store}
+	var notificationHandler = handler.NotificationHandler{Notifications: store}
+	var streamHandler = handler.StreamHandler{Changes: changes}
+	var collaborationHandler = handler.CollaborationHandler{
+		Todos:    store,
+		Lists:    store,
+		Accounts: store,
+		Changes:  changes,
+		Presence: broadcast.NewPresence(),
+	}
 	var rootRouter = mux.NewRouter()
 
 	var apiRouter = rootRouter.PathPrefix("/api/v1").Subrouter()
@@ -63,25 +172,88 @@ func configureRouter() http.Handler {
 	//apiRouter.PathPrefix("/").Handler(handler.CommonHanlder{})
 	apiRouter.StrictSlash(true)
 
+	apiRouter.Handle("/events", amw.Middleware(http.HandlerFunc(streamHandler.EventsHandler))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/collaborate", collaborationHandler.CollaborateHandler).Methods(http.MethodGet)
+
 	var accountRouter = apiRouter.PathPrefix("/account").Subrouter()
 	accountRouter.Use(amw.Middleware)
-	accountRouter.HandleFunc("/user/{id:[0-9]+}", handler.UserInfoHandler).Methods(http.MethodGet)
-	accountRouter.HandleFunc("/users", handler.UsersInfoHanlder).Methods(http.MethodGet)
+	accountRouter.HandleFunc("/user/{id:[0-9]+}", accountHandler.UserInfoHandler).Methods(http.MethodGet)
+	accountRouter.HandleFunc("/users", accountHandler.UsersInfoHanlder).Methods(http.MethodGet)
 
 	var authenticationRouter = apiRouter.PathPrefix("/authentication").Subrouter()
 	authenticationRouter.Use(lms.Middleware)
-	authenticationRouter.HandleFunc("/sign-in", handler.SignInHandler).Methods(http.MethodPost)
-	authenticationRouter.HandleFunc("/sign-up", handler.SignUpHandler).Methods(http.MethodPost)
-	authenticationRouter.HandleFunc("/refresh-token", handler.RefreshTokenHandler).Methods(http.MethodPost)
+	authenticationRouter.HandleFunc("/sign-in", authenticationHandler.SignInHandler).Methods(http.MethodPost)
+	authenticationRouter.HandleFunc("/sign-up", authenticationHandler.SignUpHandler).Methods(http.MethodPost)
+	authenticationRouter.HandleFunc("/refresh-token", authenticationHandler.RefreshTokenHandler).Methods(http.MethodPost)
 
 	var todoRouter = apiRouter.PathPrefix("/todo").Subrouter()
 	todoRouter.Use(amw.Middleware)
-	todoRouter.HandleFunc("/ping", handler.HomeHandler).Methods(http.MethodGet)
-	todoRouter.HandleFunc("/my/todos", handler.MyTodosHandler).Methods(http.MethodGet)
-	todoRouter.HandleFunc("/add", handler.AddTodoHandler).Methods(http.MethodPost)
-	todoRouter.HandleFunc("/remove/{id}", handler.RemoveTodoHandler).Methods(http.MethodDelete)
-	todoRouter.HandleFunc("/{id}", handler.GetTodoHandler).Methods(http.MethodGet)
-	todoRouter.HandleFunc("/toggle/{id}", handler.ToggleTodoHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/ping", todoHandler.HomeHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/my/todos", todoHandler.MyTodosHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/my/overdue", todoHandler.OverdueTodosHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/my/upcoming", todoHandler.UpcomingTodosHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/my/activity", todoHandler.ActivityHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/search", todoHandler.SearchTodosHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/add", todoHandler.AddTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/bulk", todoHandler.BulkTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/remove/{id}", todoHandler.RemoveTodoHandler).Methods(http.MethodDelete)
+	todoRouter.HandleFunc("/trash", todoHandler.TrashHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/restore", todoHandler.RestoreTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/history", todoHandler.HistoryHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/undo", todoHandler.UndoTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}", todoHandler.GetTodoHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}", todoHandler.UpdateTodoHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}", todoHandler.PatchTodoHandler).Methods(http.MethodPatch)
+	todoRouter.HandleFunc("/toggle/{id}", todoHandler.ToggleTodoHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/close", todoHandler.CloseTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/reopen", todoHandler.ReopenTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/workflow", todoHandler.TodoWorkflowHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/transition", todoHandler.TransitionTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/share", todoHandler.ShareTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/collaborators", todoHandler.CollaboratorsHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/assignee", todoHandler.AssignTodoHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/collaborators/{accountId:[0-9]+}", todoHandler.RevokeCollaboratorHandler).Methods(http.MethodDelete)
+	todoRouter.HandleFunc("/{id}/subtasks", todoHandler.SubtasksHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/subtasks", todoHandler.AddSubtaskHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/subtasks/order", todoHandler.ReorderSubtasksHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/parent", todoHandler.SetTodoParentHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/move", todoHandler.MoveTodoHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/attachments", todoHandler.AttachmentsHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/attachments", todoHandler.UploadAttachmentHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/attachments/{attachmentId:[0-9]+}", todoHandler.DownloadAttachmentHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/attachments/{attachmentId:[0-9]+}", todoHandler.RemoveAttachmentHandler).Methods(http.MethodDelete)
+	todoRouter.HandleFunc("/{id}/comments", todoHandler.CommentsHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/comments", todoHandler.AddCommentHandler).Methods(http.MethodPost)
+	todoRouter.HandleFunc("/{id}/comments/{commentId:[0-9]+}", todoHandler.UpdateCommentHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/comments/{commentId:[0-9]+}", todoHandler.RemoveCommentHandler).Methods(http.MethodDelete)
+	todoRouter.HandleFunc("/{id}/tags", todoHandler.TodoTagsHandler).Methods(http.MethodGet)
+	todoRouter.HandleFunc("/{id}/tags/{tagId:[0-9]+}", todoHandler.AttachTagHandler).Methods(http.MethodPut)
+	todoRouter.HandleFunc("/{id}/tags/{tagId:[0-9]+}", todoHandler.DetachTagHandler).Methods(http.MethodDelete)
+
+	var listRouter = apiRouter.PathPrefix("/lists").Subrouter()
+	listRouter.Use(amw.Middleware)
+	listRouter.HandleFunc("", listHandler.MyListsHandler).Methods(http.MethodGet)
+	listRouter.HandleFunc("", listHandler.AddListHandler).Methods(http.MethodPost)
+	listRouter.HandleFunc("/{id:[0-9]+}", listHandler.GetListHandler).Methods(http.MethodGet)
+	listRouter.HandleFunc("/{id:[0-9]+}", listHandler.UpdateListHandler).Methods(http.MethodPut)
+	listRouter.HandleFunc("/{id:[0-9]+}", listHandler.RemoveListHandler).Methods(http.MethodDelete)
+	listRouter.HandleFunc("/{id:[0-9]+}/todos", listHandler.ListTodosHandler).Methods(http.MethodGet)
+	listRouter.HandleFunc("/{id:[0-9]+}/workflow", listHandler.ListWorkflowHandler).Methods(http.MethodGet)
+	listRouter.HandleFunc("/{id:[0-9]+}/workflow", listHandler.SetListWorkflowHandler).Methods(http.MethodPut)
+
+	var tagRouter = apiRouter.PathPrefix("/tags").Subrouter()
+	tagRouter.Use(amw.Middleware)
+	tagRouter.HandleFunc("", tagHandler.MyTagsHandler).Methods(http.MethodGet)
+	tagRouter.HandleFunc("", tagHandler.AddTagHandler).Methods(http.MethodPost)
+	tagRouter.HandleFunc("/{id:[0-9]+}", tagHandler.RenameTagHandler).Methods(http.MethodPut)
+	tagRouter.HandleFunc("/{id:[0-9]+}", tagHandler.RemoveTagHandler).Methods(http.MethodDelete)
+
+	var notificationRouter = apiRouter.PathPrefix("/notifications").Subrouter()
+	notificationRouter.Use(amw.Middleware)
+	notificationRouter.HandleFunc("", notificationHandler.MyNotificationsHandler).Methods(http.MethodGet)
+	notificationRouter.HandleFunc("/unread-count", notificationHandler.UnreadNotificationsHandler).Methods(http.MethodGet)
+	notificationRouter.HandleFunc("/read", notificationHandler.ReadAllNotificationsHandler).Methods(http.MethodPost)
+	notificationRouter.HandleFunc("/{id:[0-9]+}/read", notificationHandler.ReadNotificationHandler).Methods(http.MethodPost)
 
 	rootRouter.PathPrefix("/doc").Handler(httpSwagger.WrapHandler)
 
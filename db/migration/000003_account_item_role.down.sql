@@ -0,0 +1,6 @@
+ALTER TABLE account_item
+DROP CONSTRAINT IF EXISTS account_item_pk;
+ALTER TABLE account_item
+DROP CONSTRAINT IF EXISTS account_item_role_check;
+ALTER TABLE account_item
+DROP COLUMN IF EXISTS role;
\ No newline at end of file
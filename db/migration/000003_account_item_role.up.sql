@@ -0,0 +1,14 @@
+DELETE FROM account_item a
+    USING account_item b
+WHERE a.account_id = b.account_id
+  AND a.item_id = b.item_id
+  AND a.ctid > b.ctid;
+ALTER TABLE account_item
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'owner';
+ALTER TABLE account_item
+    ADD CONSTRAINT account_item_role_check
+    CHECK (role IN ('owner', 'editor', 'viewer'));
+ALTER TABLE account_item
+    ADD CONSTRAINT account_item_pk
+    PRIMARY KEY (account_id, item_id);
+CREATE INDEX ON account_item (item_id);
\ No newline at end of file
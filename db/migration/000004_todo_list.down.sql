@@ -0,0 +1,5 @@
+ALTER TABLE item
+DROP CONSTRAINT IF EXISTS item_list_fk;
+ALTER TABLE item
+DROP COLUMN IF EXISTS list_id;
+DROP TABLE IF EXISTS todo_list;
\ No newline at end of file
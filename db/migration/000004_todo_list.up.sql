@@ -0,0 +1,22 @@
+CREATE TABLE todo_list
+(
+    id         serial PRIMARY KEY,
+    account_id INT          NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    colour     VARCHAR(7)   NOT NULL DEFAULT '',
+    archived   BOOLEAN      NOT NULL DEFAULT false,
+    created_on TIMESTAMP    NOT NULL DEFAULT current_timestamp,
+    updated_on TIMESTAMP    NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT todo_list_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE CASCADE
+);
+
+ALTER TABLE item
+    ADD COLUMN list_id INT NULL;
+ALTER TABLE item
+    ADD CONSTRAINT item_list_fk
+    FOREIGN KEY (list_id)
+    REFERENCES todo_list (id)
+    ON DELETE SET NULL;
+
+CREATE INDEX ON todo_list (account_id);
+CREATE INDEX ON item (list_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS item_search_vector_idx;
+ALTER TABLE item
+DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
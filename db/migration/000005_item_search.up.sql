@@ -0,0 +1,8 @@
+ALTER TABLE item
+    ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'B')
+    ) STORED;
+
+CREATE INDEX item_search_vector_idx ON item USING GIN (search_vector);
\ No newline at end of file
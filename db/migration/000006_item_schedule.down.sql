@@ -0,0 +1,8 @@
+ALTER TABLE item
+DROP CONSTRAINT IF EXISTS item_priority_check;
+ALTER TABLE item
+DROP COLUMN IF EXISTS remind_at;
+ALTER TABLE item
+DROP COLUMN IF EXISTS priority;
+ALTER TABLE item
+DROP COLUMN IF EXISTS due_at;
\ No newline at end of file
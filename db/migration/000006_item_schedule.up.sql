@@ -0,0 +1,12 @@
+ALTER TABLE item
+    ADD COLUMN due_at TIMESTAMP NULL;
+ALTER TABLE item
+    ADD COLUMN priority VARCHAR(8) NOT NULL DEFAULT 'normal';
+ALTER TABLE item
+    ADD COLUMN remind_at TIMESTAMP NULL;
+ALTER TABLE item
+    ADD CONSTRAINT item_priority_check
+    CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+
+CREATE INDEX ON item (due_at) WHERE NOT closed;
+CREATE INDEX ON item (remind_at) WHERE remind_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS item_series_occurrence_idx;
+ALTER TABLE item
+DROP CONSTRAINT IF EXISTS item_series_fk;
+ALTER TABLE item
+DROP COLUMN IF EXISTS occurrence;
+ALTER TABLE item
+DROP COLUMN IF EXISTS series_id;
+ALTER TABLE item
+DROP COLUMN IF EXISTS recurrence;
\ No newline at end of file
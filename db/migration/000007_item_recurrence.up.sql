@@ -0,0 +1,13 @@
+ALTER TABLE item
+    ADD COLUMN recurrence JSONB NULL;
+ALTER TABLE item
+    ADD COLUMN series_id INT NULL;
+ALTER TABLE item
+    ADD COLUMN occurrence INT NOT NULL DEFAULT 1;
+ALTER TABLE item
+    ADD CONSTRAINT item_series_fk
+    FOREIGN KEY (series_id)
+    REFERENCES item (id)
+    ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX item_series_occurrence_idx ON item (series_id, occurrence);
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE item
+DROP CONSTRAINT IF EXISTS item_parent_check;
+ALTER TABLE item
+DROP CONSTRAINT IF EXISTS item_parent_fk;
+ALTER TABLE item
+DROP COLUMN IF EXISTS subtask_position;
+ALTER TABLE item
+DROP COLUMN IF EXISTS parent_id;
\ No newline at end of file
@@ -0,0 +1,14 @@
+ALTER TABLE item
+    ADD COLUMN parent_id INT NULL;
+ALTER TABLE item
+    ADD COLUMN subtask_position INT NOT NULL DEFAULT 0;
+ALTER TABLE item
+    ADD CONSTRAINT item_parent_fk
+    FOREIGN KEY (parent_id)
+    REFERENCES item (id)
+    ON DELETE CASCADE;
+ALTER TABLE item
+    ADD CONSTRAINT item_parent_check
+    CHECK (parent_id <> id);
+
+CREATE INDEX ON item (parent_id, subtask_position);
\ No newline at end of file
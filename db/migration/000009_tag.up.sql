@@ -0,0 +1,21 @@
+CREATE TABLE tag
+(
+    id         serial PRIMARY KEY,
+    account_id INT         NOT NULL,
+    name       VARCHAR(64) NOT NULL,
+    created_on TIMESTAMP   NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT tag_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX tag_account_name_key ON tag (account_id, lower(name));
+
+CREATE TABLE item_tag
+(
+    item_id INT NOT NULL,
+    tag_id  INT NOT NULL,
+    PRIMARY KEY (item_id, tag_id),
+    CONSTRAINT item_tag_item_fk FOREIGN KEY (item_id) REFERENCES item (id) ON DELETE CASCADE,
+    CONSTRAINT item_tag_tag_fk FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE
+);
+
+CREATE INDEX ON item_tag (tag_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE item
+    ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX ON item (deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
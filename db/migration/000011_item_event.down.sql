@@ -0,0 +1 @@
+DROP TABLE IF EXISTS item_event;
\ No newline at end of file
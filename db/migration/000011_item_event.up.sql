@@ -0,0 +1,14 @@
+CREATE TABLE item_event
+(
+    id         serial PRIMARY KEY,
+    item_id    INT         NOT NULL,
+    account_id INT         NULL,
+    action     VARCHAR(16) NOT NULL,
+    before     JSONB       NULL,
+    after      JSONB       NULL,
+    created_on TIMESTAMP   NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT item_event_item_fk FOREIGN KEY (item_id) REFERENCES item (id) ON DELETE CASCADE,
+    CONSTRAINT item_event_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON item_event (item_id, id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE item_event
+DROP COLUMN IF EXISTS undone;
\ No newline at end of file
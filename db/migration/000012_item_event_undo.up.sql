@@ -0,0 +1,2 @@
+ALTER TABLE item_event
+    ADD COLUMN undone BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
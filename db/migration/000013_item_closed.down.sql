@@ -0,0 +1,4 @@
+ALTER TABLE item
+    DROP CONSTRAINT IF EXISTS item_closed_by_fk,
+    DROP COLUMN IF EXISTS closed_by,
+    DROP COLUMN IF EXISTS closed_at;
\ No newline at end of file
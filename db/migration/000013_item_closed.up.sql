@@ -0,0 +1,8 @@
+ALTER TABLE item
+    ADD COLUMN closed_at TIMESTAMP NULL,
+    ADD COLUMN closed_by INT       NULL,
+    ADD CONSTRAINT item_closed_by_fk FOREIGN KEY (closed_by) REFERENCES account (id) ON DELETE SET NULL;
+
+UPDATE item
+SET closed_at = updated_on
+WHERE closed;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE item
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE account_item
+DROP COLUMN IF EXISTS position;
\ No newline at end of file
@@ -0,0 +1,17 @@
+ALTER TABLE account_item
+    ADD COLUMN position BIGINT NULL;
+
+UPDATE account_item
+SET position = ranked.position
+FROM (SELECT account_item.account_id,
+             account_item.item_id,
+             row_number() OVER (PARTITION BY account_item.account_id ORDER BY item.updated_on, item.id) * 65536 AS position
+      FROM account_item
+               INNER JOIN item ON item.id = account_item.item_id) AS ranked
+WHERE account_item.account_id = ranked.account_id
+  AND account_item.item_id = ranked.item_id;
+
+ALTER TABLE account_item
+    ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX ON account_item (account_id, position);
\ No newline at end of file
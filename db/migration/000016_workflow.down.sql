@@ -0,0 +1,6 @@
+ALTER TABLE item
+DROP COLUMN IF EXISTS status;
+
+DROP TABLE IF EXISTS list_transition;
+
+DROP TABLE IF EXISTS list_state;
\ No newline at end of file
@@ -0,0 +1,26 @@
+CREATE TABLE list_state
+(
+    list_id  INT         NOT NULL,
+    name     VARCHAR(32) NOT NULL,
+    position INT         NOT NULL,
+    terminal BOOLEAN     NOT NULL DEFAULT false,
+    PRIMARY KEY (list_id, name),
+    CONSTRAINT list_state_list_fk FOREIGN KEY (list_id) REFERENCES todo_list (id) ON DELETE CASCADE
+);
+
+CREATE TABLE list_transition
+(
+    list_id    INT         NOT NULL,
+    from_state VARCHAR(32) NOT NULL,
+    to_state   VARCHAR(32) NOT NULL,
+    PRIMARY KEY (list_id, from_state, to_state),
+    CONSTRAINT list_transition_from_fk FOREIGN KEY (list_id, from_state) REFERENCES list_state (list_id, name) ON DELETE CASCADE,
+    CONSTRAINT list_transition_to_fk FOREIGN KEY (list_id, to_state) REFERENCES list_state (list_id, name) ON DELETE CASCADE
+);
+
+ALTER TABLE item
+    ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'backlog';
+
+UPDATE item
+SET status = 'done'
+WHERE closed;
\ No newline at end of file
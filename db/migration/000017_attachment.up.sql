@@ -0,0 +1,17 @@
+CREATE TABLE attachment
+(
+    id           serial PRIMARY KEY,
+    item_id      INT,
+    account_id   INT          NOT NULL,
+    file_name    VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size         BIGINT       NOT NULL,
+    storage_key  VARCHAR(64)  NOT NULL UNIQUE,
+    created_on   TIMESTAMP    NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT attachment_item_fk FOREIGN KEY (item_id) REFERENCES item (id) ON DELETE SET NULL,
+    CONSTRAINT attachment_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE CASCADE
+);
+
+CREATE INDEX ON attachment (item_id);
+
+CREATE INDEX ON attachment (account_id);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE comment
+(
+    id         serial PRIMARY KEY,
+    item_id    INT       NOT NULL,
+    account_id INT       NULL,
+    body       TEXT      NOT NULL,
+    created_on TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    updated_on TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT comment_item_fk FOREIGN KEY (item_id) REFERENCES item (id) ON DELETE CASCADE,
+    CONSTRAINT comment_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON comment (item_id, id);
\ No newline at end of file
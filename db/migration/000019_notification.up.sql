@@ -0,0 +1,16 @@
+CREATE TABLE notification
+(
+    id         serial PRIMARY KEY,
+    account_id INT         NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    item_id    INT         NULL,
+    actor_id   INT         NULL,
+    data       JSONB       NULL,
+    read_at    TIMESTAMP   NULL,
+    created_on TIMESTAMP   NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT notification_account_fk FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE CASCADE,
+    CONSTRAINT notification_item_fk FOREIGN KEY (item_id) REFERENCES item (id) ON DELETE CASCADE,
+    CONSTRAINT notification_actor_fk FOREIGN KEY (actor_id) REFERENCES account (id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON notification (account_id, id);
\ No newline at end of file
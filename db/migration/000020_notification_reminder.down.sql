@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notification_unread_idx;
+
+ALTER TABLE item
+DROP COLUMN IF EXISTS reminded_at;
\ No newline at end of file
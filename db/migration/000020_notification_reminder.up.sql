@@ -0,0 +1,8 @@
+ALTER TABLE item
+    ADD COLUMN reminded_at TIMESTAMP NULL;
+
+UPDATE item
+SET reminded_at = remind_at
+WHERE remind_at <= current_timestamp;
+
+CREATE INDEX notification_unread_idx ON notification (account_id) WHERE read_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE item
+DROP COLUMN IF EXISTS assignee_id;
\ No newline at end of file
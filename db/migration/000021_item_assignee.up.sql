@@ -0,0 +1,3 @@
+ALTER TABLE item
+    ADD COLUMN assignee_id INT NULL,
+    ADD CONSTRAINT item_assignee_fk FOREIGN KEY (assignee_id) REFERENCES account (id) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,144 @@
+package broadcast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is how many messages may wait for a slow subscriber
+// before it is dropped and has to resume through replay.
+const subscriptionBuffer = 64
+
+// Message is a change delivered to the accounts listed as recipients.
+type Message struct {
+	Id         string
+	Event      string
+	Data       []byte
+	Recipients []int
+	seq        int64
+}
+
+func (m Message) deliversTo(accountId int) bool {
+	for _, recipient := range m.Recipients {
+		if recipient == accountId {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives messages for one account. C is closed when the
+// subscription is dropped for falling behind or unsubscribed.
+type Subscription struct {
+	C         <-chan Message
+	accountId int
+	messages  chan Message
+}
+
+// Hub fans out messages to subscriptions and keeps the latest ones, so a
+// client which lost its connection can resume from the id it saw last.
+// Ids are only meaningful to the hub which issued them, a restarted server
+// starts a new epoch and asks clients to reload instead of replaying.
+type Hub struct {
+	mu            sync.Mutex
+	epoch         string
+	lastSeq       int64
+	evictedSeq    int64
+	replay        []Message
+	replaySize    int
+	subscriptions map[*Subscription]bool
+}
+
+func NewHub(replaySize int) *Hub {
+	return &Hub{
+		epoch:         strconv.FormatInt(time.Now().UnixNano(), 36),
+		replay:        make([]Message, 0, replaySize),
+		replaySize:    replaySize,
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+// Publish assigns the message its id, keeps it for replay and hands it to
+// subscriptions of its recipients. It never blocks on a slow subscriber.
+func (h *Hub) Publish(event string, data []byte, recipients []int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSeq++
+	var message = Message{
+		Id:         fmt.Sprintf("%s-%d", h.epoch, h.lastSeq),
+		Event:      event,
+		Data:       data,
+		Recipients: recipients,
+		seq:        h.lastSeq,
+	}
+	if len(h.replay) == h.replaySize {
+		h.evictedSeq = h.replay[0].seq
+		h.replay = append(h.replay[:0], h.replay[1:]...)
+	}
+	h.replay = append(h.replay, message)
+	for subscription := range h.subscriptions {
+		if !message.deliversTo(subscription.accountId) {
+			continue
+		}
+		select {
+		case subscription.messages <- message:
+		default:
+			h.drop(subscription)
+		}
+	}
+}
+
+// Subscribe starts delivering messages for the account. With a lastId the
+// messages published after it are returned for replay, complete is false when
+// some of them are no longer kept or the id belongs to another epoch.
+func (h *Hub) Subscribe(accountId int, lastId string) (subscription *Subscription, replay []Message, complete bool) {
+	var messages = make(chan Message, subscriptionBuffer)
+	subscription = &Subscription{C: messages, accountId: accountId, messages: messages}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions[subscription] = true
+	replay = make([]Message, 0)
+	if len(lastId) == 0 {
+		return subscription, replay, true
+	}
+	lastSeq, ok := h.sequence(lastId)
+	if !ok {
+		return subscription, replay, false
+	}
+	for _, message := range h.replay {
+		if message.seq > lastSeq && message.deliversTo(accountId) {
+			replay = append(replay, message)
+		}
+	}
+	return subscription, replay, lastSeq >= h.evictedSeq
+}
+
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(subscription)
+}
+
+// drop removes the subscription and closes its channel. Callers must hold the lock.
+func (h *Hub) drop(subscription *Subscription) {
+	if h.subscriptions[subscription] {
+		delete(h.subscriptions, subscription)
+		close(subscription.messages)
+	}
+}
+
+// sequence parses an id issued by this hub. Callers must hold the lock.
+func (h *Hub) sequence(id string) (int64, bool) {
+	epoch, value, found := strings.Cut(id, "-")
+	if !found || epoch != h.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 || seq > h.lastSeq {
+		return 0, false
+	}
+	return seq, true
+}
@@ -0,0 +1,96 @@
+package broadcast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHubReplaysFromLastEventId(t *testing.T) {
+	var hub = NewHub(3)
+	var events = []struct {
+		event      string
+		recipients []int
+	}{
+		{"created", []int{1}},
+		{"updated", []int{1}},
+		{"closed", []int{1, 2}},
+		{"shared", []int{2}},
+		{"reopened", []int{1}},
+	}
+	for _, event := range events {
+		hub.Publish(event.event, []byte("{}"), event.recipients)
+	}
+	var id = func(seq int) string {
+		return fmt.Sprintf("%s-%d", hub.epoch, seq)
+	}
+	var resumes = []struct {
+		name     string
+		lastId   string
+		events   []string
+		complete bool
+	}{
+		{"no last id", "", []string{}, true},
+		{"latest id", id(5), []string{}, true},
+		{"kept id", id(3), []string{"reopened"}, true},
+		{"last evicted id", id(2), []string{"closed", "reopened"}, true},
+		{"older evicted id", id(1), []string{"closed", "reopened"}, false},
+		{"other epoch", "0-3", []string{}, false},
+		{"future id", id(6), []string{}, false},
+		{"malformed id", hub.epoch + "-x", []string{}, false},
+		{"no sequence", hub.epoch, []string{}, false},
+	}
+	for _, resume := range resumes {
+		t.Run(resume.name, func(t *testing.T) {
+			subscription, replay, complete := hub.Subscribe(1, resume.lastId)
+			defer hub.Unsubscribe(subscription)
+			var replayed = make([]string, 0)
+			for _, message := range replay {
+				replayed = append(replayed, message.Event)
+			}
+			if !reflect.DeepEqual(replayed, resume.events) || complete != resume.complete {
+				t.Fatalf("expected %v complete %v, got %v complete %v", resume.events, resume.complete, replayed, complete)
+			}
+		})
+	}
+}
+
+func TestHubDeliversToRecipients(t *testing.T) {
+	var hub = NewHub(8)
+	alice, _, _ := hub.Subscribe(1, "")
+	bob, _, _ := hub.Subscribe(2, "")
+	hub.Publish("shared", []byte("{}"), []int{2})
+	hub.Publish("closed", []byte("{}"), []int{1, 2})
+	if message := <-alice.C; message.Event != "closed" {
+		t.Fatalf("expected alice to receive only closed, got %s", message.Event)
+	}
+	for _, event := range []string{"shared", "closed"} {
+		if message := <-bob.C; message.Event != event {
+			t.Fatalf("expected bob to receive %s, got %s", event, message.Event)
+		}
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	var hub = NewHub(subscriptionBuffer + 2)
+	subscription, _, _ := hub.Subscribe(1, "")
+	var lastId string
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish("updated", []byte("{}"), []int{1})
+		if i == subscriptionBuffer-1 {
+			lastId = fmt.Sprintf("%s-%d", hub.epoch, i+1)
+		}
+	}
+	var received = 0
+	for range subscription.C {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Fatalf("expected %d buffered messages before the drop, got %d", subscriptionBuffer, received)
+	}
+	resumed, replay, complete := hub.Subscribe(1, lastId)
+	defer hub.Unsubscribe(resumed)
+	if len(replay) != 1 || !complete {
+		t.Fatalf("expected the dropped message to be replayed, got %d complete %v", len(replay), complete)
+	}
+}
@@ -0,0 +1,156 @@
+package broadcast
+
+import (
+	"sort"
+	"sync"
+)
+
+// Viewer is an account looking at a topic, e.g. a list or a todo.
+type Viewer struct {
+	AccountId int    `json:"account-id"`
+	UserName  string `json:"user-name"`
+}
+
+// PresenceUpdate lists everyone viewing the topic after a viewer joined or left.
+type PresenceUpdate struct {
+	Topic   string
+	Viewers []Viewer
+}
+
+// Watcher is one connection of a viewer. C is signalled whenever the viewers of
+// one of its topics change, Pending then returns the current state of those topics.
+type Watcher struct {
+	C      <-chan struct{}
+	viewer Viewer
+	signal chan struct{}
+	topics map[string]bool
+	dirty  map[string]bool
+}
+
+// Presence tracks which viewers watch which topics. Updates are coalesced per
+// watcher, so a slow connection only ever misses intermediate states.
+type Presence struct {
+	mu     sync.Mutex
+	topics map[string]map[*Watcher]bool
+}
+
+func NewPresence() *Presence {
+	return &Presence{topics: make(map[string]map[*Watcher]bool)}
+}
+
+func (p *Presence) NewWatcher(viewer Viewer) *Watcher {
+	var signal = make(chan struct{}, 1)
+	return &Watcher{
+		C:      signal,
+		viewer: viewer,
+		signal: signal,
+		topics: make(map[string]bool),
+		dirty:  make(map[string]bool),
+	}
+}
+
+// Join adds the watcher to the topic and reports whether it was not watching it yet.
+func (p *Presence) Join(topic string, watcher *Watcher) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if watcher.topics[topic] {
+		return false
+	}
+	if p.topics[topic] == nil {
+		p.topics[topic] = make(map[*Watcher]bool)
+	}
+	p.topics[topic][watcher] = true
+	watcher.topics[topic] = true
+	p.changed(topic)
+	return true
+}
+
+// Leave removes the watcher from the topic and reports whether it was watching it.
+func (p *Presence) Leave(topic string, watcher *Watcher) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.leave(topic, watcher)
+}
+
+// LeaveAll removes the watcher from every topic, e.g. when its connection closes.
+func (p *Presence) LeaveAll(watcher *Watcher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for topic := range watcher.topics {
+		p.leave(topic, watcher)
+	}
+}
+
+// Watching reports whether the watcher watches the topic.
+func (p *Presence) Watching(topic string, watcher *Watcher) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return watcher.topics[topic]
+}
+
+// Topics returns how many topics the watcher watches.
+func (p *Presence) Topics(watcher *Watcher) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(watcher.topics)
+}
+
+// Pending returns the viewers of the topics which changed since the last call.
+func (p *Presence) Pending(watcher *Watcher) []PresenceUpdate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var updates = make([]PresenceUpdate, 0, len(watcher.dirty))
+	for topic := range watcher.dirty {
+		if watcher.topics[topic] {
+			updates = append(updates, PresenceUpdate{Topic: topic, Viewers: p.viewers(topic)})
+		}
+		delete(watcher.dirty, topic)
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Topic < updates[j].Topic
+	})
+	return updates
+}
+
+// leave is Leave for callers holding the lock.
+func (p *Presence) leave(topic string, watcher *Watcher) bool {
+	if !watcher.topics[topic] {
+		return false
+	}
+	delete(watcher.topics, topic)
+	delete(watcher.dirty, topic)
+	delete(p.topics[topic], watcher)
+	if len(p.topics[topic]) == 0 {
+		delete(p.topics, topic)
+		return true
+	}
+	p.changed(topic)
+	return true
+}
+
+// changed marks the topic for every watcher of it and wakes them up. Callers must hold the lock.
+func (p *Presence) changed(topic string) {
+	for watcher := range p.topics[topic] {
+		watcher.dirty[topic] = true
+		select {
+		case watcher.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// viewers lists the distinct accounts watching the topic by user name. Callers must hold the lock.
+func (p *Presence) viewers(topic string) []Viewer {
+	var seen = make(map[int]bool)
+	var viewers = make([]Viewer, 0, len(p.topics[topic]))
+	for watcher := range p.topics[topic] {
+		if !seen[watcher.viewer.AccountId] {
+			seen[watcher.viewer.AccountId] = true
+			viewers = append(viewers, watcher.viewer)
+		}
+	}
+	sort.Slice(viewers, func(i, j int) bool {
+		return viewers[i].UserName < viewers[j].UserName
+	})
+	return viewers
+}
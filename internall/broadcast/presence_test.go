@@ -0,0 +1,71 @@
+package broadcast
+
+import (
+	"reflect"
+	"testing"
+)
+
+// drain consumes the signal of the watcher and returns its pending updates.
+func drain(t *testing.T, presence *Presence, watcher *Watcher) []PresenceUpdate {
+	t.Helper()
+	select {
+	case <-watcher.C:
+	default:
+		t.Fatal("expected the watcher to be signalled")
+	}
+	return presence.Pending(watcher)
+}
+
+func TestPresenceCoalescesJoinAndLeave(t *testing.T) {
+	var presence = NewPresence()
+	var alice = presence.NewWatcher(Viewer{AccountId: 1, UserName: "alice"})
+	var bob = presence.NewWatcher(Viewer{AccountId: 2, UserName: "bob"})
+	var carol = presence.NewWatcher(Viewer{AccountId: 3, UserName: "carol"})
+	presence.Join("todo:1", alice)
+	drain(t, presence, alice)
+	presence.Join("todo:1", bob)
+	presence.Join("todo:1", carol)
+	presence.Leave("todo:1", carol)
+	var expected = []PresenceUpdate{{
+		Topic:   "todo:1",
+		Viewers: []Viewer{{AccountId: 1, UserName: "alice"}, {AccountId: 2, UserName: "bob"}},
+	}}
+	if updates := drain(t, presence, alice); !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("expected one update with the final viewers %v, got %v", expected, updates)
+	}
+	select {
+	case <-alice.C:
+		t.Fatal("expected the three changes to wake the watcher once")
+	default:
+	}
+}
+
+func TestPresenceListsAccountOnce(t *testing.T) {
+	var presence = NewPresence()
+	var phone = presence.NewWatcher(Viewer{AccountId: 1, UserName: "alice"})
+	var laptop = presence.NewWatcher(Viewer{AccountId: 1, UserName: "alice"})
+	presence.Join("list:1", phone)
+	presence.Join("list:1", laptop)
+	var updates = drain(t, presence, phone)
+	if len(updates) != 1 || len(updates[0].Viewers) != 1 {
+		t.Fatalf("expected alice listed once, got %v", updates)
+	}
+}
+
+func TestPresenceLeaveDropsPendingUpdate(t *testing.T) {
+	var presence = NewPresence()
+	var alice = presence.NewWatcher(Viewer{AccountId: 1, UserName: "alice"})
+	var bob = presence.NewWatcher(Viewer{AccountId: 2, UserName: "bob"})
+	presence.Join("todo:1", alice)
+	presence.Join("todo:2", alice)
+	drain(t, presence, alice)
+	presence.Join("todo:1", bob)
+	presence.Leave("todo:1", alice)
+	if updates := drain(t, presence, alice); len(updates) != 0 {
+		t.Fatalf("expected no update about a topic the watcher left, got %v", updates)
+	}
+	presence.LeaveAll(alice)
+	if presence.Topics(alice) != 0 || presence.Watching("todo:2", alice) {
+		t.Fatal("expected the watcher to watch nothing after leaving all topics")
+	}
+}
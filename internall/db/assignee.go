@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+)
+
+// AssignTodo makes the account the assignee of the todo, a nil assigneeId unassigns it.
+// Whether the assignee has access to the todo is checked by the caller.
+func (p *PostgresStore) AssignTodo(ctx context.Context, todoId int, assigneeId *int, version *int) (*model.Todo, error) {
+	var todo = &model.Todo{}
+	err := scanTodo(p.connectionDB.QueryRow(ctx, "UPDATE item SET assignee_id = $2, updated_on = current_timestamp, "+
+		"version = version + 1 WHERE id = $1 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) "+
+		"RETURNING "+todoColumns, todoId, assigneeId, version,
+	), todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return todo, missingTodoError(ctx, p.connectionDB, todoId, version)
+	}
+	return todo, err
+}
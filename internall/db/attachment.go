@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+)
+
+const attachmentColumns = "attachment.id, attachment.item_id, attachment.account_id, attachment.file_name, " +
+	"attachment.content_type, attachment.size, attachment.storage_key, attachment.created_on"
+
+func scanAttachment(row pgx.Row, attachment *model.Attachment) error {
+	return row.Scan(
+		&attachment.Id,
+		&attachment.TodoId,
+		&attachment.AccountId,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.CreatedOn,
+	)
+}
+
+// CreateAttachment locks the account row, so concurrent uploads of one account
+// cannot pass the quota check together.
+func (p *PostgresStore) CreateAttachment(ctx context.Context, attachment *model.Attachment, quota int64) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	if _, err = tx.Exec(ctx, "SELECT id FROM account WHERE id = $1 FOR UPDATE", attachment.AccountId); err != nil {
+		return err
+	}
+	var usage int64
+	if err = tx.QueryRow(ctx, "SELECT coalesce(sum(size), 0) FROM attachment WHERE account_id = $1",
+		attachment.AccountId).Scan(&usage); err != nil {
+		return err
+	}
+	if usage+attachment.Size > quota {
+		err = ErrQuotaExceeded
+		return err
+	}
+	err = scanAttachment(tx.QueryRow(ctx, "INSERT INTO attachment (item_id, account_id, file_name, content_type, size, storage_key) "+
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+attachmentColumns,
+		attachment.TodoId, attachment.AccountId, attachment.FileName, attachment.ContentType, attachment.Size, attachment.StorageKey,
+	), attachment)
+	return err
+}
+
+func (p *PostgresStore) GetAttachments(ctx context.Context, todoId int) ([]model.Attachment, error) {
+	var attachments = make([]model.Attachment, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+attachmentColumns+" FROM attachment WHERE item_id = $1 ORDER BY id", todoId)
+	if err != nil {
+		return attachments, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attachment = model.Attachment{}
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return attachments, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (p *PostgresStore) GetAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error) {
+	var attachment = &model.Attachment{}
+	err := scanAttachment(p.connectionDB.QueryRow(ctx, "SELECT "+attachmentColumns+" FROM attachment WHERE id = $1 AND item_id = $2",
+		attachmentId, todoId,
+	), attachment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return attachment, ErrNotFound
+	}
+	return attachment, err
+}
+
+// RemoveAttachmentBy deletes the metadata and returns it, so the caller can remove the blob.
+func (p *PostgresStore) RemoveAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error) {
+	var attachment = &model.Attachment{}
+	err := scanAttachment(p.connectionDB.QueryRow(ctx, "DELETE FROM attachment WHERE id = $1 AND item_id = $2 RETURNING "+attachmentColumns,
+		attachmentId, todoId,
+	), attachment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return attachment, ErrNotFound
+	}
+	return attachment, err
+}
+
+func (p *PostgresStore) GetAttachmentUsage(ctx context.Context, accountId int) (int64, error) {
+	var usage int64
+	err := p.connectionDB.QueryRow(ctx, "SELECT coalesce(sum(size), 0) FROM attachment WHERE account_id = $1", accountId).Scan(&usage)
+	return usage, err
+}
+
+// PurgeDetachedAttachments relies on item_id being set to NULL when the todo is purged from the trash.
+func (p *PostgresStore) PurgeDetachedAttachments(ctx context.Context) ([]string, error) {
+	var keys = make([]string, 0)
+	rows, err := p.connectionDB.Query(ctx, "DELETE FROM attachment WHERE item_id IS NULL RETURNING storage_key")
+	if err != nil {
+		return keys, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
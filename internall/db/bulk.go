@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/jackc/pgx/v4"
+)
+
+// ApplyBulk runs all operations in one transaction and returns what each of them changed.
+// Every todo is locked when its operation checks access and version, so the checks hold
+// until the transaction ends. On failure nothing is applied and a *BulkError is returned.
+func (p *PostgresStore) ApplyBulk(ctx context.Context, userId int, operations []request.BulkOperation) ([]BulkChange, error) {
+	var changes = make([]BulkChange, 0, len(operations))
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return changes, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	for index, operation := range operations {
+		var change BulkChange
+		if change, err = applyBulkOperation(ctx, tx, userId, operation); err != nil {
+			err = bulkError(index, operation, err)
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func applyBulkOperation(ctx context.Context, tx pgx.Tx, userId int, operation request.BulkOperation) (BulkChange, error) {
+	var change BulkChange
+	var todoId = operation.TodoId
+	var err error
+	if operation.Op == request.BulkCreate {
+		if err = checkBulkList(ctx, tx, userId, operation.Todo.ListId, nil); err != nil {
+			return change, err
+		}
+		if todoId, err = insertTodo(ctx, tx, userId, *operation.Todo); err != nil {
+			return change, err
+		}
+	} else if change.Before, err = lockBulkTodo(ctx, tx, userId, operation); err != nil {
+		return change, err
+	}
+	switch operation.Op {
+	case request.BulkClose, request.BulkReopen:
+		_, change.Next, _, err = setTodoClosed(ctx, tx, todoId, userId, operation.Op == request.BulkClose, nil)
+	case request.BulkDelete:
+		err = execOnTodo(ctx, tx, trashSubtreeQuery, todoId, nil)
+	case request.BulkMove:
+		if err = checkBulkList(ctx, tx, userId, operation.ListId, change.Before.ListId); err != nil {
+			return change, err
+		}
+		err = execOnTodo(ctx, tx, "UPDATE item SET list_id = $2, updated_on = current_timestamp, version = version + 1, "+
+			"status = "+fitStatusSQL("$2", "status", "closed")+" "+
+			"WHERE id = $1 AND deleted_at IS NULL", todoId, operation.ListId)
+	case request.BulkTag:
+		if err = checkBulkTag(ctx, tx, userId, operation.TagId); err != nil {
+			return change, err
+		}
+		err = execOnTodo(ctx, tx, attachTagQuery, todoId, operation.TagId)
+	}
+	if err != nil {
+		return change, err
+	}
+	err = scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM item WHERE id = $1 AND (deleted_at IS NOT NULL) = $2",
+		todoId, operation.Op == request.BulkDelete), &change.Todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return change, ErrNotFound
+	}
+	return change, err
+}
+
+// lockBulkTodo locks the todo of the operation for the rest of the transaction and returns
+// it as it is before the operation. It fails unless the account holds the role the operation
+// requires and the todo is still at the version of the operation.
+func lockBulkTodo(ctx context.Context, tx pgx.Tx, userId int, operation request.BulkOperation) (*model.Todo, error) {
+	var todo = &model.Todo{}
+	var role *model.Role
+	err := scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+", "+
+		"(SELECT role FROM account_item WHERE item_id = item.id AND account_id = $2) "+
+		"FROM item WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", operation.TodoId, userId,
+	), todo, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role == nil || !role.Allows(operation.RequiredRole()) {
+		return nil, ErrAccessDenied
+	}
+	if operation.Version != nil && todo.Version != *operation.Version {
+		return nil, ErrVersionMismatch
+	}
+	return todo, nil
+}
+
+// checkBulkList makes sure a todo only goes into a list of the account, keeping the current
+// list is always allowed. The list is locked so it cannot be removed before the commit.
+func checkBulkList(ctx context.Context, tx pgx.Tx, userId int, listId *int, currentListId *int) error {
+	if listId == nil || (currentListId != nil && *listId == *currentListId) {
+		return nil
+	}
+	var accountId int
+	err := tx.QueryRow(ctx, "SELECT account_id FROM todo_list WHERE id = $1 FOR SHARE", *listId).Scan(&accountId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrNotFound
+	}
+	if err == nil && accountId != userId {
+		err = ErrAccessDenied
+	}
+	if err != nil {
+		return &BulkError{Kind: "List", Id: *listId, Err: err}
+	}
+	return nil
+}
+
+// checkBulkTag makes sure the tag belongs to the account and locks it until the commit.
+func checkBulkTag(ctx context.Context, tx pgx.Tx, userId int, tagId int) error {
+	var accountId int
+	err := tx.QueryRow(ctx, "SELECT account_id FROM tag WHERE id = $1 FOR SHARE", tagId).Scan(&accountId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrNotFound
+	}
+	if err == nil && accountId != userId {
+		err = ErrAccessDenied
+	}
+	if err != nil {
+		return &BulkError{Kind: "Tag", Id: tagId, Err: err}
+	}
+	return nil
+}
+
+// bulkError numbers the failure of an operation. Failures which do not name
+// a list or a tag are about the todo of the operation.
+func bulkError(index int, operation request.BulkOperation, err error) *BulkError {
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) {
+		bulkErr = &BulkError{Kind: "Todo", Id: operation.TodoId, Err: err}
+	}
+	bulkErr.Index = index
+	return bulkErr
+}
+
+// execOnTodo runs a statement keyed by the todo id and reports ErrNotFound when no row was touched.
+func execOnTodo(ctx context.Context, tx pgx.Tx, query string, todoId int, args ...interface{}) error {
+	tag, err := tx.Exec(ctx, query, append([]interface{}{todoId}, args...)...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/jackc/pgx/v4"
+	"strings"
+)
+
+const commentColumns = "comment.id, comment.item_id, comment.account_id, coalesce(account.username, ''), " +
+	"comment.body, comment.created_on, comment.updated_on"
+
+func scanComment(row pgx.Row, comment *model.Comment) error {
+	return row.Scan(
+		&comment.Id,
+		&comment.TodoId,
+		&comment.AuthorId,
+		&comment.AuthorName,
+		&comment.Body,
+		&comment.CreatedOn,
+		&comment.UpdatedOn,
+	)
+}
+
+// CreateCommentFor inserts the comment and reads it back joined with its author.
+func (p *PostgresStore) CreateCommentFor(ctx context.Context, todoId int, userId int, commentForm request.CommentForm) (*model.Comment, error) {
+	var comment = &model.Comment{}
+	err := scanComment(p.connectionDB.QueryRow(ctx, "WITH created AS ("+
+		"INSERT INTO comment (item_id, account_id, body) VALUES ($1, $2, $3) RETURNING *"+
+		") SELECT "+commentColumns+" FROM created AS comment LEFT JOIN account ON account.id = comment.account_id",
+		todoId, userId, strings.TrimSpace(commentForm.Body),
+	), comment)
+	return comment, err
+}
+
+// GetComments returns comments of the todo oldest first, an afterId of 0 starts from the first comment.
+func (p *PostgresStore) GetComments(ctx context.Context, todoId int, afterId int, limit int) ([]model.Comment, error) {
+	var comments = make([]model.Comment, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+commentColumns+" "+
+		"FROM comment LEFT JOIN account ON account.id = comment.account_id "+
+		"WHERE comment.item_id = $1 AND comment.id > $2 ORDER BY comment.id LIMIT $3", todoId, afterId, limit,
+	)
+	if err != nil {
+		return comments, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var comment = model.Comment{}
+		if err := scanComment(rows, &comment); err != nil {
+			return comments, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (p *PostgresStore) GetCommentBy(ctx context.Context, todoId int, commentId int) (*model.Comment, error) {
+	var comment = &model.Comment{}
+	err := scanComment(p.connectionDB.QueryRow(ctx, "SELECT "+commentColumns+" "+
+		"FROM comment LEFT JOIN account ON account.id = comment.account_id "+
+		"WHERE comment.id = $1 AND comment.item_id = $2", commentId, todoId,
+	), comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return comment, ErrNotFound
+	}
+	return comment, err
+}
+
+func (p *PostgresStore) UpdateCommentBy(ctx context.Context, commentId int, commentForm request.CommentForm) (*model.Comment, error) {
+	var comment = &model.Comment{}
+	err := scanComment(p.connectionDB.QueryRow(ctx, "WITH updated AS ("+
+		"UPDATE comment SET body = $1, updated_on = current_timestamp WHERE id = $2 RETURNING *"+
+		") SELECT "+commentColumns+" FROM updated AS comment LEFT JOIN account ON account.id = comment.account_id",
+		strings.TrimSpace(commentForm.Body), commentId,
+	), comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return comment, ErrNotFound
+	}
+	return comment, err
+}
+
+func (p *PostgresStore) RemoveCommentBy(ctx context.Context, commentId int) error {
+	tag, err := p.connectionDB.Exec(ctx, "DELETE FROM comment WHERE id = $1", commentId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -8,170 +8,503 @@ import (
 	"github.com/IosifSuzuki/todo/internall/model/request"
 	"github.com/IosifSuzuki/todo/internall/utility"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"time"
 )
 
-var connectionDB *pgx.Conn
+type PostgresStore struct {
+	connectionDB *pgxpool.Pool
+}
 
-func ConnectToDB() {
+func ConnectToDB() *PostgresStore {
 	var urlConnection = utility.Config.DB.URL()
-	connection, err := pgx.Connect(context.Background(), urlConnection)
+	poolConfig, err := pgxpool.ParseConfig(urlConnection)
+	if err != nil {
+		logger.Fatal("Error occurred during parse db config", zap.Error(err))
+	}
+	if utility.Config.DB.MaxConnections > 0 {
+		poolConfig.MaxConns = utility.Config.DB.MaxConnections
+	}
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
 	if err != nil {
 		logger.Fatal("Error occurred during connection to db", zap.Error(err))
 	}
-	connectionDB = connection
+	return &PostgresStore{connectionDB: pool}
 }
 
-func CloseConnectionToDB() error {
+func (p *PostgresStore) Close() error {
 	logger.Info("Will disconnect from data base")
-	return connectionDB.Close(context.Background())
+	p.connectionDB.Close()
+	return nil
 }
 
-func CreateAccount(registrationForm request.RegistrationForm) (*model.AccountModel, error) {
+func (p *PostgresStore) CreateAccount(ctx context.Context, registrationForm request.RegistrationForm) (*model.AccountModel, error) {
 	hashPassword, err := utility.HashPassword(registrationForm.Password)
 	if err != nil {
 		return nil, err
 	}
 	var accountId int
-	err = connectionDB.QueryRow(
-		context.Background(),
+	err = p.connectionDB.QueryRow(
+		ctx,
 		"INSERT INTO account (username, hash_password, email) VALUES($1, $2, $3) RETURNING id",
 		registrationForm.UserName, hashPassword, registrationForm.Email,
 	).Scan(&accountId)
 	if err != nil {
 		return nil, err
 	}
-	return GetUserById(accountId)
+	return p.GetUserById(ctx, accountId)
 }
 
-func Authentication(authenticationForm request.AuthenticationForm) (*model.AccountModel, error) {
-	var accountModel *model.AccountModel
-	var err error
-	if accountModel, err = GetUserByUserName(authenticationForm.UserName); err != nil {
+func (p *PostgresStore) Authentication(ctx context.Context, authenticationForm request.AuthenticationForm) (*model.AccountModel, error) {
+	var accountModel = new(model.AccountModel)
+	err := p.connectionDB.QueryRow(
+		ctx,
+		"SELECT id, username, email, created_on, hash_password FROM account WHERE username = $1",
+		authenticationForm.UserName,
+	).Scan(&accountModel.Id, &accountModel.UserName, &accountModel.Email, &accountModel.CreatedAt, &accountModel.HashPassword)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
-	if utility.CheckHashPassword(authenticationForm.Password, accountModel.HashPassword) {
-		return nil, errors.New("access denied")
+	if !utility.CheckHashPassword(authenticationForm.Password, accountModel.HashPassword) {
+		return nil, ErrAccessDenied
 	}
+	accountModel.HashPassword = ""
 	return accountModel, nil
 }
 
-func GetUserById(id int) (*model.AccountModel, error) {
+func (p *PostgresStore) GetUserById(ctx context.Context, id int) (*model.AccountModel, error) {
 	var accountModel = new(model.AccountModel)
-	err := connectionDB.QueryRow(
-		context.Background(),
+	err := p.connectionDB.QueryRow(
+		ctx,
 		"SELECT id, username, email, created_on FROM account WHERE id = $1",
 		id,
 	).Scan(&accountModel.Id, &accountModel.UserName, &accountModel.Email, &accountModel.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return accountModel, ErrNotFound
+	}
 	return accountModel, err
 }
 
-func GetUserByUserName(userName string) (*model.AccountModel, error) {
+func (p *PostgresStore) GetUserByUserName(ctx context.Context, userName string) (*model.AccountModel, error) {
 	var accountModel = new(model.AccountModel)
-	err := connectionDB.QueryRow(
-		context.Background(),
+	err := p.connectionDB.QueryRow(
+		ctx,
 		"SELECT id, username, email, created_on FROM account WHERE username = $1",
 		userName,
 	).Scan(&accountModel.Id, &accountModel.UserName, &accountModel.Email, &accountModel.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return accountModel, ErrNotFound
+	}
 	return accountModel, err
 }
 
-func GetTodosBy(userId int) ([]model.Todo, error) {
-	rows, err := connectionDB.Query(context.Background(), "SELECT id, title, description, created_on, updated_on, closed "+
-		"FROM item INNER JOIN account_item ON account_item.item_id = id "+
-		"WHERE account_item.account_id = $1 ORDER BY updated_on", userId,
-	)
+const todoColumns = "item.id, item.title, item.description, item.created_on, item.updated_on, item.closed, item.closed_at, item.closed_by, item.status, " +
+	"item.list_id, item.due_at, item.priority, item.remind_at, item.recurrence, item.series_id, item.occurrence, " +
+	"item.parent_id, item.assignee_id, item.deleted_at, item.version, " + progressColumn
+
+// progressColumn computes the share of closed direct subtasks, it is NULL for todos without subtasks.
+// Subtasks in the trash are not counted.
+const progressColumn = "(SELECT round(100.0 * count(*) FILTER (WHERE subtask.closed) / count(*))::int " +
+	"FROM item AS subtask WHERE subtask.parent_id = item.id AND subtask.deleted_at IS NULL HAVING count(*) > 0)"
+
+// scanPositionedTodos reads todoColumns followed by account_item.position.
+func scanPositionedTodos(rows pgx.Rows) ([]model.Todo, error) {
 	defer rows.Close()
 	var todos = make([]model.Todo, 0)
-	if err != nil {
-		return todos, err
+	for rows.Next() {
+		var todoModel = model.Todo{}
+		if err := scanTodo(rows, &todoModel, &todoModel.Position); err != nil {
+			return todos, err
+		}
+		todos = append(todos, todoModel)
 	}
+	return todos, rows.Err()
+}
+
+// scanTodo reads todoColumns into todo, extra destinations receive the columns selected after them.
+func scanTodo(row pgx.Row, todo *model.Todo, extra ...interface{}) error {
+	var dest = []interface{}{
+		&todo.Id,
+		&todo.Title,
+		&todo.Description,
+		&todo.CreatedOn,
+		&todo.UpdatedOn,
+		&todo.Closed,
+		&todo.ClosedAt,
+		&todo.ClosedBy,
+		&todo.Status,
+		&todo.ListId,
+		&todo.DueAt,
+		&todo.Priority,
+		&todo.RemindAt,
+		&todo.Recurrence,
+		&todo.SeriesId,
+		&todo.Occurrence,
+		&todo.ParentId,
+		&todo.AssigneeId,
+		&todo.DeletedAt,
+		&todo.Version,
+		&todo.Progress,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// utcTime converts t to UTC, TIMESTAMP columns drop the zone of stored values.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	var value = t.UTC()
+	return &value
+}
+
+func scanTodos(rows pgx.Rows) ([]model.Todo, error) {
+	defer rows.Close()
+	var todos = make([]model.Todo, 0)
 	for rows.Next() {
 		var todoModel = model.Todo{}
-		err = rows.Scan(
-			&todoModel.Id,
-			&todoModel.Title,
-			&todoModel.Description,
-			&todoModel.CreatedOn,
-			&todoModel.UpdatedOn,
-			&todoModel.Closed,
-		)
-		if err != nil {
+		if err := scanTodo(rows, &todoModel); err != nil {
 			return todos, err
 		}
 		todos = append(todos, todoModel)
 	}
-	return todos, err
+	return todos, rows.Err()
+}
+
+func (p *PostgresStore) GetTodosBy(ctx context.Context, userId int) ([]model.Todo, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+", account_item.position "+
+		"FROM item INNER JOIN account_item ON account_item.item_id = item.id "+
+		"WHERE account_item.account_id = $1 AND item.deleted_at IS NULL ORDER BY account_item.position, item.id", userId,
+	)
+	if err != nil {
+		return make([]model.Todo, 0), err
+	}
+	return scanPositionedTodos(rows)
 }
 
-func CreteTodoFor(userId int, todoForm request.TodoForm) (*model.Todo, error) {
+func (p *PostgresStore) CreteTodoFor(ctx context.Context, userId int, todoForm request.TodoForm) (*model.Todo, error) {
 	var todo = &model.Todo{}
-	tx, err := connectionDB.BeginTx(context.Background(), pgx.TxOptions{})
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return todo, err
 	}
 	defer func() {
 		if err != nil {
-			tx.Rollback(context.Background())
+			tx.Rollback(ctx)
 		} else {
-			tx.Commit(context.Background())
+			tx.Commit(ctx)
 		}
 	}()
+	todoId, err := insertTodo(ctx, tx, userId, todoForm)
+	if err != nil {
+		return todo, err
+	}
+	err = scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM item WHERE id = $1", todoId), todo)
+	return todo, err
+}
+
+// insertTodo creates a todo owned by the account inside the transaction and returns its id.
+func insertTodo(ctx context.Context, tx pgx.Tx, userId int, todoForm request.TodoForm) (int, error) {
 	var todoId int
-	err = tx.QueryRow(context.Background(),
-		"INSERT INTO item(title, description, closed) VALUES($1, $2, $3) RETURNING id",
-		todoForm.Title, todoForm.Description, false,
+	err := tx.QueryRow(ctx,
+		"INSERT INTO item(title, description, closed, list_id, due_at, priority, remind_at, recurrence, status) "+
+			"VALUES($1, $2, $3, $4, $5, $6, $7, $8, "+initialStateSQL("$4")+") RETURNING id",
+		todoForm.Title, todoForm.Description, false, todoForm.ListId,
+		utcTime(todoForm.DueAt), todoForm.EffectivePriority(), utcTime(todoForm.RemindAt), todoForm.Recurrence,
 	).Scan(&todoId)
 	if err != nil {
-		return todo, err
+		return todoId, err
 	}
-	_, err = tx.Exec(context.Background(), "INSERT INTO account_item (account_id, item_id) VALUES ($1, $2)",
-		userId, todoId)
+	_, err = tx.Exec(ctx, "INSERT INTO account_item (account_id, item_id, role, position) "+
+		"VALUES ($1, $2, $3, "+nextPositionSQL("$1")+")", userId, todoId, model.RoleOwner)
+	return todoId, err
+}
+
+// ToggleTodoFor flips closed in a single statement, so concurrent toggles do not cancel each other out.
+// Closing a recurring todo generates its next occurrence in the same transaction, it is returned as next.
+func (p *PostgresStore) ToggleTodoFor(ctx context.Context, todoId int, userId int, version *int) (*model.Todo, *model.Todo, error) {
+	var todo = &model.Todo{}
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return todo, err
+		return todo, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	err = scanTodo(tx.QueryRow(ctx, "UPDATE item SET closed = NOT closed, version = version + 1, "+
+		"status = CASE WHEN closed THEN "+initialStateSQL("item.list_id")+" ELSE "+terminalStateSQL("item.list_id")+" END, "+
+		"closed_at = CASE WHEN closed THEN NULL ELSE current_timestamp END, "+
+		"closed_by = CASE WHEN closed THEN NULL ELSE $2 END "+
+		"WHERE id = $1 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) RETURNING "+todoColumns,
+		todoId, userId, version,
+	), todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = missingTodoError(ctx, tx, todoId, version)
+	}
+	if err != nil {
+		return todo, nil, err
+	}
+	var next *model.Todo
+	next, err = insertNextOccurrence(ctx, tx, *todo)
+	return todo, next, err
+}
+
+// setClosedQuery closes ($2 true) or reopens the todo $1 on behalf of account $3 while it is
+// at version $4 or $4 is null. Setting the state the todo is already in keeps closed_at and
+// closed_by, the previous state is returned after the todo columns. The row is locked first
+// so it is read at its latest version.
+var setClosedQuery = "WITH previous AS (SELECT id, closed FROM item " +
+	"WHERE id = $1 AND deleted_at IS NULL AND ($4::int IS NULL OR version = $4) FOR UPDATE) " +
+	"UPDATE item SET closed = $2, " +
+	"closed_at = CASE WHEN NOT $2 THEN NULL WHEN previous.closed THEN item.closed_at ELSE current_timestamp END, " +
+	"closed_by = CASE WHEN NOT $2 THEN NULL WHEN previous.closed THEN item.closed_by ELSE $3 END, " +
+	"version = CASE WHEN previous.closed = $2 THEN item.version ELSE item.version + 1 END, " +
+	"status = CASE WHEN previous.closed = $2 THEN item.status WHEN $2 THEN " + terminalStateSQL("item.list_id") +
+	" ELSE " + initialStateSQL("item.list_id") + " END " +
+	"FROM previous WHERE item.id = previous.id RETURNING " + todoColumns + ", previous.closed"
+
+// SetTodoClosed closes or reopens the todo and reports whether its state changed.
+// Closing a recurring todo generates its next occurrence in the same transaction, it is returned as next.
+func (p *PostgresStore) SetTodoClosed(ctx context.Context, todoId int, userId int, closed bool, version *int) (*model.Todo, *model.Todo, bool, error) {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return &model.Todo{}, nil, false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	var todo, next *model.Todo
+	var changed bool
+	todo, next, changed, err = setTodoClosed(ctx, tx, todoId, userId, closed, version)
+	return todo, next, changed, err
+}
+
+// setTodoClosed is SetTodoClosed inside the transaction.
+func setTodoClosed(ctx context.Context, tx pgx.Tx, todoId int, userId int, closed bool, version *int) (*model.Todo, *model.Todo, bool, error) {
+	var todo = &model.Todo{}
+	var wasClosed bool
+	err := scanTodo(tx.QueryRow(ctx, setClosedQuery, todoId, closed, userId, version), todo, &wasClosed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return todo, nil, false, missingTodoError(ctx, tx, todoId, version)
+	}
+	if err != nil || wasClosed == closed {
+		return todo, nil, false, err
+	}
+	var next *model.Todo
+	if closed {
+		next, err = insertNextOccurrence(ctx, tx, *todo)
+	}
+	return todo, next, true, err
+}
+
+// UpdateTodoBy replaces the fields of the todo. When version is set the todo is only
+// updated if it is still at that version, otherwise ErrVersionMismatch is returned.
+func (p *PostgresStore) UpdateTodoBy(ctx context.Context, todoId int, todoForm request.TodoForm, version *int) (*model.Todo, error) {
+	var todo = &model.Todo{}
+	err := scanTodo(p.connectionDB.QueryRow(ctx, "UPDATE item SET title = $1, description = $2, list_id = $3, "+
+		"due_at = $4, priority = $5, remind_at = $6, recurrence = $7, updated_on = current_timestamp, "+
+		"status = "+fitStatusSQL("$3", "status", "closed")+", "+
+		"version = version + 1 WHERE id = $8 AND deleted_at IS NULL AND ($9::int IS NULL OR version = $9) RETURNING "+todoColumns,
+		todoForm.Title, todoForm.Description, todoForm.ListId,
+		utcTime(todoForm.DueAt), todoForm.EffectivePriority(), utcTime(todoForm.RemindAt), todoForm.Recurrence, todoId,
+		version,
+	), todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return todo, missingTodoError(ctx, p.connectionDB, todoId, version)
 	}
-	err = tx.QueryRow(context.Background(), "SELECT id, title, description, created_on, updated_on, closed "+
-		"FROM item WHERE id = $1", todoId,
-	).Scan(&todo.Id, &todo.Title, &todo.Description, &todo.CreatedOn, &todo.UpdatedOn, &todo.Closed)
 	return todo, err
 }
 
-func ToggleTodoFor(todoId int) error {
-	var closed bool
-	err := connectionDB.QueryRow(context.Background(), "SELECT closed FROM item WHERE id = $1", todoId).Scan(&closed)
+// missingTodoError tells why a statement guarded by the version did not touch the todo,
+// it is ErrVersionMismatch when the todo still exists and ErrNotFound otherwise.
+func missingTodoError(ctx context.Context, q querier, todoId int, version *int) error {
+	if version == nil {
+		return ErrNotFound
+	}
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM item WHERE id = $1 AND deleted_at IS NULL)", todoId).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrNotFound
+}
+
+// trashSubtreeQuery moves the todo $1 together with its subtasks into the trash while it is
+// at version $2 or $2 is null. They all share one deleted_at so a restore brings back
+// exactly this subtree.
+const trashSubtreeQuery = "WITH RECURSIVE subtree AS (" +
+	"SELECT id FROM item WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2) " +
+	"UNION ALL SELECT item.id FROM item INNER JOIN subtree ON item.parent_id = subtree.id " +
+	"WHERE item.deleted_at IS NULL" +
+	") UPDATE item SET deleted_at = current_timestamp, version = version + 1 FROM subtree WHERE item.id = subtree.id"
+
+func (p *PostgresStore) RemoveTodoBy(ctx context.Context, todoId int, version *int) error {
+	tag, err := p.connectionDB.Exec(ctx, trashSubtreeQuery, todoId, version)
 	if err != nil {
 		return err
 	}
-	_, err = connectionDB.Exec(context.Background(), "UPDATE item SET closed = $1 WHERE id = $2", !closed, todoId)
+	if tag.RowsAffected() == 0 && version != nil {
+		return missingTodoError(ctx, p.connectionDB, todoId, version)
+	}
+	return nil
+}
+
+func (p *PostgresStore) GetTodoBy(ctx context.Context, todoId int) (*model.Todo, error) {
+	var todo = &model.Todo{}
+	err := scanTodo(p.connectionDB.QueryRow(ctx, "SELECT "+todoColumns+" FROM item "+
+		"WHERE id = $1 AND deleted_at IS NULL", todoId), todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return todo, ErrNotFound
+	}
+	return todo, err
+}
+
+func (p *PostgresStore) GetTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error) {
+	return p.todoRole(ctx, userId, todoId, false)
+}
+
+// todoRole looks up the role of the account on a todo which is in the trash or not,
+// todos on the other side are reported as not found.
+func (p *PostgresStore) todoRole(ctx context.Context, userId int, todoId int, trashed bool) (model.Role, error) {
+	var exists bool
+	var role *model.Role
+	err := p.connectionDB.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM item WHERE id = $1 AND (deleted_at IS NOT NULL) = $3), "+
+		"(SELECT role FROM account_item WHERE item_id = $1 AND account_id = $2)", todoId, userId, trashed,
+	).Scan(&exists, &role)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", ErrNotFound
+	}
+	if role == nil {
+		return "", ErrAccessDenied
+	}
+	return *role, nil
+}
+
+func (p *PostgresStore) ShareTodo(ctx context.Context, todoId int, accountId int, role model.Role) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	if role != model.RoleOwner {
+		if err = ensureAnotherOwner(ctx, tx, todoId, accountId); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO account_item (account_id, item_id, role, position) "+
+		"VALUES ($1, $2, $3, "+nextPositionSQL("$1")+") ON CONFLICT (account_id, item_id) DO UPDATE SET role = EXCLUDED.role",
+		accountId, todoId, role,
+	)
 	return err
 }
 
-func RemoveTodoBy(todoId int) error {
-	_, err := connectionDB.Exec(context.Background(), "DELETE FROM item WHERE id = $1", todoId)
+func (p *PostgresStore) GetCollaborators(ctx context.Context, todoId int) ([]model.Collaborator, error) {
+	var collaborators = make([]model.Collaborator, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT account.id, account.username, account_item.role "+
+		"FROM account_item INNER JOIN account ON account.id = account_item.account_id "+
+		"WHERE account_item.item_id = $1 ORDER BY account.id", todoId,
+	)
+	if err != nil {
+		return collaborators, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var collaborator = model.Collaborator{}
+		if err = rows.Scan(&collaborator.AccountId, &collaborator.UserName, &collaborator.Role); err != nil {
+			return collaborators, err
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	return collaborators, rows.Err()
+}
+
+// RevokeTodoAccess removes the account from the collaborators of the todo, the todo
+// is unassigned when the account was its assignee.
+func (p *PostgresStore) RevokeTodoAccess(ctx context.Context, todoId int, accountId int) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	if err = ensureAnotherOwner(ctx, tx, todoId, accountId); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM account_item WHERE item_id = $1 AND account_id = $2", todoId, accountId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = ErrNotFound
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE item SET assignee_id = NULL, version = version + 1 "+
+		"WHERE id = $1 AND assignee_id = $2", todoId, accountId)
 	return err
 }
 
-func GetTodoBy(todoId int) (*model.Todo, error) {
-	var todo = &model.Todo{}
-	err := connectionDB.QueryRow(context.Background(), "SELECT id, title, description, created_on, updated_on, closed "+
-		"FROM item WHERE id = $1", todoId,
-	).Scan(&todo.Id, &todo.Title, &todo.Description, &todo.CreatedOn, &todo.UpdatedOn, &todo.Closed)
-	return todo, err
+// ensureAnotherOwner fails with ErrLastOwner when accountId is the only owner of the todo.
+func ensureAnotherOwner(ctx context.Context, tx pgx.Tx, todoId int, accountId int) error {
+	var otherOwners int
+	var isOwner bool
+	err := tx.QueryRow(ctx, "SELECT "+
+		"count(*) FILTER (WHERE account_id <> $2 AND role = 'owner'), "+
+		"coalesce(bool_or(account_id = $2 AND role = 'owner'), false) "+
+		"FROM (SELECT account_id, role FROM account_item WHERE item_id = $1 FOR UPDATE) AS links", todoId, accountId,
+	).Scan(&otherOwners, &isOwner)
+	if err != nil {
+		return err
+	}
+	if isOwner && otherOwners == 0 {
+		return ErrLastOwner
+	}
+	return nil
 }
 
-func GetAccountBy(userId int) (*model.AccountModel, error) {
+func (p *PostgresStore) GetAccountBy(ctx context.Context, userId int) (*model.AccountModel, error) {
 	var account = &model.AccountModel{}
-	err := connectionDB.QueryRow(
-		context.Background(),
+	err := p.connectionDB.QueryRow(
+		ctx,
 		"SELECT id, username, email, created_on FROM account WHERE id = $1",
 		userId,
 	).Scan(&account.Id, &account.UserName, &account.Email, &account.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return account, ErrNotFound
+	}
 	return account, err
 }
 
-func GetAccounts() ([]model.AccountModel, error) {
+func (p *PostgresStore) GetAccounts(ctx context.Context) ([]model.AccountModel, error) {
 	var accounts = make([]model.AccountModel, 0)
-	rows, err := connectionDB.Query(context.Background(), "SELECT id, username, email, created_on FROM account")
+	rows, err := p.connectionDB.Query(ctx, "SELECT id, username, email, created_on FROM account")
 	if err != nil {
 		return accounts, err
 	}
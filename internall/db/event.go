@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+const eventColumns = "item_event.id, item_event.item_id, item_event.account_id, coalesce(account.username, ''), " +
+	"item_event.action, item_event.before, item_event.after, item_event.undone, item_event.created_on"
+
+// scanEvent reads eventColumns into event, extra destinations receive the columns selected after them.
+func scanEvent(row pgx.Row, event *model.TodoEvent, extra ...interface{}) error {
+	var before, after []byte
+	var dest = []interface{}{
+		&event.Id,
+		&event.TodoId,
+		&event.ActorId,
+		&event.ActorName,
+		&event.Action,
+		&before,
+		&after,
+		&event.Undone,
+		&event.CreatedOn,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	event.Before, event.After = before, after
+	return nil
+}
+
+func scanEvents(rows pgx.Rows) ([]model.TodoEvent, error) {
+	defer rows.Close()
+	var events = make([]model.TodoEvent, 0)
+	for rows.Next() {
+		var event = model.TodoEvent{}
+		if err := scanEvent(rows, &event); err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// marshalSnapshot encodes a before or after snapshot, nil is stored as NULL.
+func marshalSnapshot(snapshot interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (p *PostgresStore) AddTodoEvent(ctx context.Context, todoId int, actorId int, action model.TodoAction, before interface{}, after interface{}) error {
+	beforeData, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+	_, err = p.connectionDB.Exec(ctx, "INSERT INTO item_event (item_id, account_id, action, before, after) "+
+		"VALUES ($1, $2, $3, $4, $5)", todoId, actorId, action, beforeData, afterData,
+	)
+	return err
+}
+
+// GetTodoHistory returns events of the todo newest first, a beforeId of 0 starts from the latest event.
+func (p *PostgresStore) GetTodoHistory(ctx context.Context, todoId int, beforeId int, limit int) ([]model.TodoEvent, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+eventColumns+" "+
+		"FROM item_event LEFT JOIN account ON account.id = item_event.account_id "+
+		"WHERE item_event.item_id = $1 AND ($2 = 0 OR item_event.id < $2) "+
+		"ORDER BY item_event.id DESC LIMIT $3", todoId, beforeId, limit,
+	)
+	if err != nil {
+		return make([]model.TodoEvent, 0), err
+	}
+	return scanEvents(rows)
+}
+
+// GetActivityFeed returns events of all todos the account has access to, newest first.
+func (p *PostgresStore) GetActivityFeed(ctx context.Context, userId int, beforeId int, limit int) ([]model.TodoEvent, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+eventColumns+" "+
+		"FROM item_event INNER JOIN account_item ON account_item.item_id = item_event.item_id "+
+		"LEFT JOIN account ON account.id = item_event.account_id "+
+		"WHERE account_item.account_id = $1 AND ($2 = 0 OR item_event.id < $2) "+
+		"ORDER BY item_event.id DESC LIMIT $3", userId, beforeId, limit,
+	)
+	if err != nil {
+		return make([]model.TodoEvent, 0), err
+	}
+	return scanEvents(rows)
+}
+
+// GetLastTodoEvent returns the latest not undone event of the todo with one of
+// the given actions and whether it happened within the window.
+func (p *PostgresStore) GetLastTodoEvent(ctx context.Context, todoId int, actions []model.TodoAction, window time.Duration) (*model.TodoEvent, bool, error) {
+	var event = &model.TodoEvent{}
+	var recent bool
+	var names = make([]string, 0, len(actions))
+	for _, action := range actions {
+		names = append(names, string(action))
+	}
+	err := scanEvent(p.connectionDB.QueryRow(ctx, "SELECT "+eventColumns+", "+
+		"item_event.created_on > current_timestamp - $3::interval "+
+		"FROM item_event LEFT JOIN account ON account.id = item_event.account_id "+
+		"WHERE item_event.item_id = $1 AND item_event.action = ANY($2) AND NOT item_event.undone "+
+		"ORDER BY item_event.id DESC LIMIT 1", todoId, names, window,
+	), event, &recent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return event, false, ErrNotFound
+	}
+	return event, recent, err
+}
+
+// MarkTodoEventUndone flags the event as undone, it fails with ErrAlreadyUndone
+// when a concurrent undo got there first.
+func (p *PostgresStore) MarkTodoEventUndone(ctx context.Context, eventId int) error {
+	tag, err := p.connectionDB.Exec(ctx, "UPDATE item_event SET undone = true WHERE id = $1 AND NOT undone", eventId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyUndone
+	}
+	return nil
+}
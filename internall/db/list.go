@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/jackc/pgx/v4"
+)
+
+const listColumns = "id, account_id, name, colour, archived, created_on, updated_on"
+
+func scanList(row pgx.Row, list *model.List) error {
+	return row.Scan(
+		&list.Id,
+		&list.AccountId,
+		&list.Name,
+		&list.Colour,
+		&list.Archived,
+		&list.CreatedOn,
+		&list.UpdatedOn,
+	)
+}
+
+func (p *PostgresStore) GetListsBy(ctx context.Context, userId int, includeArchived bool) ([]model.List, error) {
+	var lists = make([]model.List, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+listColumns+" FROM todo_list "+
+		"WHERE account_id = $1 AND ($2 OR NOT archived) ORDER BY id", userId, includeArchived,
+	)
+	if err != nil {
+		return lists, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var list = model.List{}
+		if err = scanList(rows, &list); err != nil {
+			return lists, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (p *PostgresStore) CreateListFor(ctx context.Context, userId int, listForm request.ListForm) (*model.List, error) {
+	var list = &model.List{}
+	err := scanList(p.connectionDB.QueryRow(ctx, "INSERT INTO todo_list (account_id, name, colour, archived) "+
+		"VALUES ($1, $2, $3, $4) RETURNING "+listColumns,
+		userId, listForm.Name, listForm.Colour, listForm.Archived,
+	), list)
+	return list, err
+}
+
+func (p *PostgresStore) GetListBy(ctx context.Context, listId int) (*model.List, error) {
+	var list = &model.List{}
+	err := scanList(p.connectionDB.QueryRow(ctx, "SELECT "+listColumns+" FROM todo_list WHERE id = $1", listId), list)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return list, ErrNotFound
+	}
+	return list, err
+}
+
+func (p *PostgresStore) UpdateListBy(ctx context.Context, listId int, listForm request.ListForm) (*model.List, error) {
+	var list = &model.List{}
+	err := scanList(p.connectionDB.QueryRow(ctx, "UPDATE todo_list SET name = $1, colour = $2, archived = $3, "+
+		"updated_on = current_timestamp WHERE id = $4 RETURNING "+listColumns,
+		listForm.Name, listForm.Colour, listForm.Archived, listId,
+	), list)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return list, ErrNotFound
+	}
+	return list, err
+}
+
+// RemoveListBy deletes the list, its todos leave it and move into the default workflow.
+func (p *PostgresStore) RemoveListBy(ctx context.Context, listId int) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	_, err = tx.Exec(ctx, "UPDATE item SET list_id = NULL, status = "+fitStatusSQL("NULL", "status", "closed")+
+		" WHERE list_id = $1", listId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM todo_list WHERE id = $1", listId)
+	return err
+}
+
+func (p *PostgresStore) GetTodosByList(ctx context.Context, userId int, listId int) ([]model.Todo, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+", account_item.position "+
+		"FROM item INNER JOIN account_item ON account_item.item_id = item.id "+
+		"WHERE account_item.account_id = $1 AND item.list_id = $2 AND item.deleted_at IS NULL "+
+		"ORDER BY account_item.position, item.id", userId, listId,
+	)
+	if err != nil {
+		return make([]model.Todo, 0), err
+	}
+	return scanPositionedTodos(rows)
+}
@@ -0,0 +1,498 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"sort"
+	"sync"
+	"time"
+)
+
+type accountItem struct {
+	accountId int
+	itemId    int
+	role      model.Role
+	position  int64
+}
+
+type itemTag struct {
+	itemId int
+	tagId  int
+}
+
+// MemoryStore keeps accounts and todos in process memory. It mirrors the
+// postgres schema and is meant for tests and local demos.
+type MemoryStore struct {
+	mu                 sync.RWMutex
+	lastAccountId      int
+	lastItemId         int
+	lastListId         int
+	lastTagId          int
+	lastEventId        int
+	lastAttachmentId   int
+	lastCommentId      int
+	lastNotificationId int
+	accounts           map[int]model.AccountModel
+	items              map[int]model.Todo
+	accountItems       []accountItem
+	lists              map[int]model.List
+	workflows          map[int]model.Workflow
+	positions          map[int]int
+	tags               map[int]model.Tag
+	itemTags           map[itemTag]bool
+	events             []model.TodoEvent
+	attachments        map[int]model.Attachment
+	comments           map[int]model.Comment
+	notifications      []model.Notification
+	reminded           map[int]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:    make(map[int]model.AccountModel),
+		items:       make(map[int]model.Todo),
+		lists:       make(map[int]model.List),
+		workflows:   make(map[int]model.Workflow),
+		positions:   make(map[int]int),
+		tags:        make(map[int]model.Tag),
+		itemTags:    make(map[itemTag]bool),
+		attachments: make(map[int]model.Attachment),
+		comments:    make(map[int]model.Comment),
+		reminded:    make(map[int]time.Time),
+	}
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) CreateAccount(ctx context.Context, registrationForm request.RegistrationForm) (*model.AccountModel, error) {
+	hashPassword, err := utility.HashPassword(registrationForm.Password)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, account := range m.accounts {
+		if account.UserName == registrationForm.UserName {
+			return nil, errors.New("username already exists")
+		}
+	}
+	m.lastAccountId++
+	var account = model.AccountModel{
+		Id:           m.lastAccountId,
+		UserName:     registrationForm.UserName,
+		Email:        registrationForm.Email,
+		CreatedAt:    time.Now(),
+		HashPassword: hashPassword,
+	}
+	m.accounts[account.Id] = account
+	account.HashPassword = ""
+	return &account, nil
+}
+
+func (m *MemoryStore) Authentication(ctx context.Context, authenticationForm request.AuthenticationForm) (*model.AccountModel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, account := range m.accounts {
+		if account.UserName != authenticationForm.UserName {
+			continue
+		}
+		if !utility.CheckHashPassword(authenticationForm.Password, account.HashPassword) {
+			return nil, ErrAccessDenied
+		}
+		account.HashPassword = ""
+		return &account, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) GetUserById(ctx context.Context, id int) (*model.AccountModel, error) {
+	return m.GetAccountBy(ctx, id)
+}
+
+func (m *MemoryStore) GetUserByUserName(ctx context.Context, userName string) (*model.AccountModel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, account := range m.accounts {
+		if account.UserName == userName {
+			account.HashPassword = ""
+			return &account, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) GetAccountBy(ctx context.Context, userId int) (*model.AccountModel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	account, ok := m.accounts[userId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	account.HashPassword = ""
+	return &account, nil
+}
+
+func (m *MemoryStore) GetAccounts(ctx context.Context) ([]model.AccountModel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var accounts = make([]model.AccountModel, 0, len(m.accounts))
+	for _, account := range m.accounts {
+		account.HashPassword = ""
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Id < accounts[j].Id
+	})
+	return accounts, nil
+}
+
+func (m *MemoryStore) GetTodosBy(ctx context.Context, userId int) ([]model.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var todos = make([]model.Todo, 0)
+	for _, link := range m.accountItems {
+		if link.accountId != userId {
+			continue
+		}
+		if todo, ok := m.items[link.itemId]; ok && todo.DeletedAt == nil {
+			todo.Position = link.position
+			todos = append(todos, m.withProgress(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if todos[i].Position == todos[j].Position {
+			return todos[i].Id < todos[j].Id
+		}
+		return todos[i].Position < todos[j].Position
+	})
+	return todos, nil
+}
+
+func (m *MemoryStore) CreteTodoFor(ctx context.Context, userId int, todoForm request.TodoForm) (*model.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[userId]; !ok {
+		return &model.Todo{}, ErrNotFound
+	}
+	var todo = m.createTodo(userId, todoForm)
+	return &todo, nil
+}
+
+// createTodo adds a todo owned by the account. Callers must hold the lock.
+func (m *MemoryStore) createTodo(userId int, todoForm request.TodoForm) model.Todo {
+	m.lastItemId++
+	var now = time.Now()
+	var todo = model.Todo{
+		Id:          m.lastItemId,
+		Title:       todoForm.Title,
+		Description: todoForm.Description,
+		CreatedOn:   now,
+		UpdatedOn:   now,
+		Closed:      false,
+		Status:      m.workflowOf(todoForm.ListId).Initial(),
+		ListId:      todoForm.ListId,
+		DueAt:       utcTime(todoForm.DueAt),
+		Priority:    todoForm.EffectivePriority(),
+		RemindAt:    utcTime(todoForm.RemindAt),
+		Recurrence:  todoForm.Recurrence,
+		Occurrence:  1,
+		Version:     1,
+	}
+	m.items[todo.Id] = todo
+	m.accountItems = append(m.accountItems, accountItem{
+		accountId: userId,
+		itemId:    todo.Id,
+		role:      model.RoleOwner,
+		position:  m.nextPosition(userId),
+	})
+	return todo
+}
+
+func (m *MemoryStore) ToggleTodoFor(ctx context.Context, todoId int, userId int, version *int) (*model.Todo, *model.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, err := m.todoAt(todoId, version)
+	if err != nil {
+		return &model.Todo{}, nil, err
+	}
+	todo, next := m.closeTodo(todo, userId, !todo.Closed)
+	return &todo, next, nil
+}
+
+func (m *MemoryStore) SetTodoClosed(ctx context.Context, todoId int, userId int, closed bool, version *int) (*model.Todo, *model.Todo, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, err := m.todoAt(todoId, version)
+	if err != nil {
+		return &model.Todo{}, nil, false, err
+	}
+	var changed = todo.Closed != closed
+	todo, next := m.closeTodo(todo, userId, closed)
+	return &todo, next, changed, nil
+}
+
+// todoAt returns the todo outside of the trash, with a version it must still be at
+// that version. Callers must hold the lock.
+func (m *MemoryStore) todoAt(todoId int, version *int) (model.Todo, error) {
+	todo, ok := m.items[todoId]
+	if !ok || todo.DeletedAt != nil {
+		return todo, ErrNotFound
+	}
+	if version != nil && todo.Version != *version {
+		return todo, ErrVersionMismatch
+	}
+	return todo, nil
+}
+
+// closeTodo is setClosed which also generates the next occurrence when it closes
+// a recurring todo. Callers must hold the lock.
+func (m *MemoryStore) closeTodo(todo model.Todo, userId int, closed bool) (model.Todo, *model.Todo) {
+	var wasClosed = todo.Closed
+	todo = m.setClosed(todo, userId, closed)
+	if !closed || wasClosed {
+		return todo, nil
+	}
+	return todo, m.createNextOccurrence(todo)
+}
+
+// setClosed stores the closed state of the todo, closing an already closed todo keeps
+// closed at and closed by. Callers must hold the lock.
+func (m *MemoryStore) setClosed(todo model.Todo, userId int, closed bool) model.Todo {
+	if closed != todo.Closed {
+		var workflow = m.workflowOf(todo.ListId)
+		todo.Status = workflow.Initial()
+		if closed {
+			todo.Status = workflow.FirstTerminal()
+		}
+		todo.Version++
+	}
+	if closed && !todo.Closed {
+		var now = time.Now()
+		todo.ClosedAt = &now
+		todo.ClosedBy = &userId
+	} else if !closed {
+		todo.ClosedAt = nil
+		todo.ClosedBy = nil
+	}
+	todo.Closed = closed
+	m.items[todo.Id] = todo
+	return m.withProgress(todo)
+}
+
+func (m *MemoryStore) UpdateTodoBy(ctx context.Context, todoId int, todoForm request.TodoForm, version *int) (*model.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, err := m.todoAt(todoId, version)
+	if err != nil {
+		return &model.Todo{}, err
+	}
+	todo.Title = todoForm.Title
+	todo.Description = todoForm.Description
+	todo.ListId = todoForm.ListId
+	todo.Status = m.fitStatus(todo)
+	todo.DueAt = utcTime(todoForm.DueAt)
+	todo.Priority = todoForm.EffectivePriority()
+	todo.RemindAt = utcTime(todoForm.RemindAt)
+	todo.Recurrence = todoForm.Recurrence
+	todo.UpdatedOn = time.Now()
+	todo.Version++
+	m.items[todoId] = todo
+	todo = m.withProgress(todo)
+	return &todo, nil
+}
+
+func (m *MemoryStore) RemoveTodoBy(ctx context.Context, todoId int, version *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.todoAt(todoId, version); err != nil && version != nil {
+		return err
+	}
+	m.trashTodo(todoId)
+	return nil
+}
+
+// trashTodo moves the todo together with its subtasks into the trash and
+// reports whether the todo was found outside of it. Callers must hold the lock.
+func (m *MemoryStore) trashTodo(todoId int) bool {
+	todo, ok := m.items[todoId]
+	if !ok || todo.DeletedAt != nil {
+		return false
+	}
+	var now = time.Now().UTC()
+	var subtree = map[int]bool{todoId: true}
+	for found := true; found; {
+		found = false
+		for id, todo := range m.items {
+			if !subtree[id] && todo.DeletedAt == nil && todo.ParentId != nil && subtree[*todo.ParentId] {
+				subtree[id] = true
+				found = true
+			}
+		}
+	}
+	for id := range subtree {
+		todo := m.items[id]
+		todo.DeletedAt = &now
+		todo.Version++
+		m.items[id] = todo
+	}
+	return true
+}
+
+// removeItems permanently deletes todos with everything linked to them. Callers must hold the lock.
+func (m *MemoryStore) removeItems(removed map[int]bool) {
+	for id := range removed {
+		delete(m.items, id)
+		delete(m.positions, id)
+		delete(m.reminded, id)
+	}
+	for link := range m.itemTags {
+		if removed[link.itemId] {
+			delete(m.itemTags, link)
+		}
+	}
+	var accountItems = m.accountItems[:0]
+	for _, link := range m.accountItems {
+		if !removed[link.itemId] {
+			accountItems = append(accountItems, link)
+		}
+	}
+	m.accountItems = accountItems
+	var events = m.events[:0]
+	for _, event := range m.events {
+		if !removed[event.TodoId] {
+			events = append(events, event)
+		}
+	}
+	m.events = events
+	for id, comment := range m.comments {
+		if removed[comment.TodoId] {
+			delete(m.comments, id)
+		}
+	}
+	var notifications = m.notifications[:0]
+	for _, notification := range m.notifications {
+		if notification.TodoId == nil || !removed[*notification.TodoId] {
+			notifications = append(notifications, notification)
+		}
+	}
+	m.notifications = notifications
+	for id, attachment := range m.attachments {
+		if removed[attachment.TodoId] {
+			attachment.TodoId = 0
+			m.attachments[id] = attachment
+		}
+	}
+}
+
+func (m *MemoryStore) GetTodoBy(ctx context.Context, todoId int) (*model.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	todo, ok := m.items[todoId]
+	if !ok || todo.DeletedAt != nil {
+		return &model.Todo{}, ErrNotFound
+	}
+	todo = m.withProgress(todo)
+	return &todo, nil
+}
+
+func (m *MemoryStore) GetTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error) {
+	return m.todoRole(userId, todoId, false)
+}
+
+func (m *MemoryStore) todoRole(userId int, todoId int, trashed bool) (model.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if todo, ok := m.items[todoId]; !ok || (todo.DeletedAt != nil) != trashed {
+		return "", ErrNotFound
+	}
+	for _, link := range m.accountItems {
+		if link.accountId == userId && link.itemId == todoId {
+			return link.role, nil
+		}
+	}
+	return "", ErrAccessDenied
+}
+
+func (m *MemoryStore) ShareTodo(ctx context.Context, todoId int, accountId int, role model.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[todoId]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.accounts[accountId]; !ok {
+		return ErrNotFound
+	}
+	if role != model.RoleOwner && m.isLastOwner(todoId, accountId) {
+		return ErrLastOwner
+	}
+	for i, link := range m.accountItems {
+		if link.accountId == accountId && link.itemId == todoId {
+			m.accountItems[i].role = role
+			return nil
+		}
+	}
+	m.accountItems = append(m.accountItems, accountItem{
+		accountId: accountId,
+		itemId:    todoId,
+		role:      role,
+		position:  m.nextPosition(accountId),
+	})
+	return nil
+}
+
+func (m *MemoryStore) GetCollaborators(ctx context.Context, todoId int) ([]model.Collaborator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var collaborators = make([]model.Collaborator, 0)
+	for _, link := range m.accountItems {
+		if link.itemId != todoId {
+			continue
+		}
+		collaborators = append(collaborators, model.Collaborator{
+			AccountId: link.accountId,
+			UserName:  m.accounts[link.accountId].UserName,
+			Role:      link.role,
+		})
+	}
+	sort.Slice(collaborators, func(i, j int) bool {
+		return collaborators[i].AccountId < collaborators[j].AccountId
+	})
+	return collaborators, nil
+}
+
+func (m *MemoryStore) RevokeTodoAccess(ctx context.Context, todoId int, accountId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.isLastOwner(todoId, accountId) {
+		return ErrLastOwner
+	}
+	for i, link := range m.accountItems {
+		if link.accountId == accountId && link.itemId == todoId {
+			m.accountItems = append(m.accountItems[:i], m.accountItems[i+1:]...)
+			m.unassign(todoId, accountId)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) isLastOwner(todoId int, accountId int) bool {
+	var isOwner bool
+	var otherOwners int
+	for _, link := range m.accountItems {
+		if link.itemId != todoId || link.role != model.RoleOwner {
+			continue
+		}
+		if link.accountId == accountId {
+			isOwner = true
+		} else {
+			otherOwners++
+		}
+	}
+	return isOwner && otherOwners == 0
+}
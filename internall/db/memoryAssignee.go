@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"time"
+)
+
+func (m *MemoryStore) AssignTodo(ctx context.Context, todoId int, assigneeId *int, version *int) (*model.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, err := m.todoAt(todoId, version)
+	if err != nil {
+		return &model.Todo{}, err
+	}
+	todo.AssigneeId = assigneeId
+	todo.UpdatedOn = time.Now()
+	todo.Version++
+	m.items[todoId] = todo
+	todo = m.withProgress(todo)
+	return &todo, nil
+}
+
+// unassign clears the assignee of the todo when it is the account. Callers must hold the lock.
+func (m *MemoryStore) unassign(todoId int, accountId int) {
+	todo, ok := m.items[todoId]
+	if !ok || todo.AssigneeId == nil || *todo.AssigneeId != accountId {
+		return
+	}
+	todo.AssigneeId = nil
+	todo.Version++
+	m.items[todoId] = todo
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) CreateAttachment(ctx context.Context, attachment *model.Attachment, quota int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[attachment.TodoId]; !ok {
+		return ErrNotFound
+	}
+	if m.attachmentUsage(attachment.AccountId)+attachment.Size > quota {
+		return ErrQuotaExceeded
+	}
+	m.lastAttachmentId++
+	attachment.Id = m.lastAttachmentId
+	attachment.CreatedOn = time.Now()
+	m.attachments[attachment.Id] = *attachment
+	return nil
+}
+
+func (m *MemoryStore) GetAttachments(ctx context.Context, todoId int) ([]model.Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var attachments = make([]model.Attachment, 0)
+	for _, attachment := range m.attachments {
+		if attachment.TodoId == todoId {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].Id < attachments[j].Id
+	})
+	return attachments, nil
+}
+
+func (m *MemoryStore) GetAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	attachment, ok := m.attachments[attachmentId]
+	if !ok || attachment.TodoId != todoId {
+		return &model.Attachment{}, ErrNotFound
+	}
+	return &attachment, nil
+}
+
+func (m *MemoryStore) RemoveAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attachment, ok := m.attachments[attachmentId]
+	if !ok || attachment.TodoId != todoId {
+		return &model.Attachment{}, ErrNotFound
+	}
+	delete(m.attachments, attachmentId)
+	return &attachment, nil
+}
+
+func (m *MemoryStore) GetAttachmentUsage(ctx context.Context, accountId int) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.attachmentUsage(accountId), nil
+}
+
+// PurgeDetachedAttachments removes attachments whose todo was purged, removeItems detaches them.
+func (m *MemoryStore) PurgeDetachedAttachments(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys = make([]string, 0)
+	for id, attachment := range m.attachments {
+		if attachment.TodoId == 0 {
+			keys = append(keys, attachment.StorageKey)
+			delete(m.attachments, id)
+		}
+	}
+	return keys, nil
+}
+
+// attachmentUsage sums the size of every attachment uploaded by the account. Callers must hold the lock.
+func (m *MemoryStore) attachmentUsage(accountId int) int64 {
+	var usage int64
+	for _, attachment := range m.attachments {
+		if attachment.AccountId == accountId {
+			usage += attachment.Size
+		}
+	}
+	return usage
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"time"
+)
+
+// memorySnapshot holds the state bulk operations may change, so a failed bulk request can be rolled back.
+type memorySnapshot struct {
+	lastItemId   int
+	items        map[int]model.Todo
+	accountItems []accountItem
+	itemTags     map[itemTag]bool
+}
+
+func (m *MemoryStore) ApplyBulk(ctx context.Context, userId int, operations []request.BulkOperation) ([]BulkChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var snapshot = m.snapshot()
+	var changes = make([]BulkChange, 0, len(operations))
+	for index, operation := range operations {
+		change, err := m.applyBulkOperation(userId, operation)
+		if err != nil {
+			m.restore(snapshot)
+			return changes, bulkError(index, operation, err)
+		}
+		change.Todo = m.withProgress(change.Todo)
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func (m *MemoryStore) applyBulkOperation(userId int, operation request.BulkOperation) (BulkChange, error) {
+	var change BulkChange
+	if operation.Op == request.BulkCreate {
+		if _, ok := m.accounts[userId]; !ok {
+			return change, ErrNotFound
+		}
+		if err := m.checkBulkList(userId, operation.Todo.ListId, nil); err != nil {
+			return change, err
+		}
+		change.Todo = m.createTodo(userId, *operation.Todo)
+		return change, nil
+	}
+	todo, err := m.bulkTodo(userId, operation)
+	if err != nil {
+		return change, err
+	}
+	var before = m.withProgress(todo)
+	change.Before = &before
+	switch operation.Op {
+	case request.BulkClose, request.BulkReopen:
+		change.Todo, change.Next = m.closeTodo(todo, userId, operation.Op == request.BulkClose)
+		return change, nil
+	case request.BulkDelete:
+		m.trashTodo(todo.Id)
+		todo = m.items[todo.Id]
+	case request.BulkMove:
+		if err := m.checkBulkList(userId, operation.ListId, todo.ListId); err != nil {
+			return change, err
+		}
+		todo.ListId = operation.ListId
+		todo.Status = m.fitStatus(todo)
+		todo.UpdatedOn = time.Now()
+		todo.Version++
+	case request.BulkTag:
+		if tag, ok := m.tags[operation.TagId]; !ok {
+			return change, &BulkError{Kind: "Tag", Id: operation.TagId, Err: ErrNotFound}
+		} else if tag.AccountId != userId {
+			return change, &BulkError{Kind: "Tag", Id: operation.TagId, Err: ErrAccessDenied}
+		}
+		m.itemTags[itemTag{itemId: todo.Id, tagId: operation.TagId}] = true
+	}
+	m.items[todo.Id] = todo
+	change.Todo = todo
+	return change, nil
+}
+
+// bulkTodo is lockBulkTodo for the memory store. Callers must hold the lock.
+func (m *MemoryStore) bulkTodo(userId int, operation request.BulkOperation) (model.Todo, error) {
+	todo, err := m.todoAt(operation.TodoId, nil)
+	if err != nil {
+		return todo, err
+	}
+	var role model.Role
+	for _, link := range m.accountItems {
+		if link.accountId == userId && link.itemId == todo.Id {
+			role = link.role
+		}
+	}
+	if !role.Allows(operation.RequiredRole()) {
+		return todo, ErrAccessDenied
+	}
+	if operation.Version != nil && todo.Version != *operation.Version {
+		return todo, ErrVersionMismatch
+	}
+	return todo, nil
+}
+
+// checkBulkList is checkBulkList of the postgres store. Callers must hold the lock.
+func (m *MemoryStore) checkBulkList(userId int, listId *int, currentListId *int) error {
+	if listId == nil || (currentListId != nil && *listId == *currentListId) {
+		return nil
+	}
+	if list, ok := m.lists[*listId]; !ok {
+		return &BulkError{Kind: "List", Id: *listId, Err: ErrNotFound}
+	} else if list.AccountId != userId {
+		return &BulkError{Kind: "List", Id: *listId, Err: ErrAccessDenied}
+	}
+	return nil
+}
+
+func (m *MemoryStore) snapshot() memorySnapshot {
+	var snapshot = memorySnapshot{
+		lastItemId:   m.lastItemId,
+		items:        make(map[int]model.Todo, len(m.items)),
+		accountItems: append([]accountItem(nil), m.accountItems...),
+		itemTags:     make(map[itemTag]bool, len(m.itemTags)),
+	}
+	for id, todo := range m.items {
+		snapshot.items[id] = todo
+	}
+	for link := range m.itemTags {
+		snapshot.itemTags[link] = true
+	}
+	return snapshot
+}
+
+func (m *MemoryStore) restore(snapshot memorySnapshot) {
+	m.lastItemId = snapshot.lastItemId
+	m.items = snapshot.items
+	m.accountItems = snapshot.accountItems
+	m.itemTags = snapshot.itemTags
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStore) CreateCommentFor(ctx context.Context, todoId int, userId int, commentForm request.CommentForm) (*model.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[todoId]; !ok {
+		return &model.Comment{}, ErrNotFound
+	}
+	m.lastCommentId++
+	var now = time.Now()
+	var comment = model.Comment{
+		Id:        m.lastCommentId,
+		TodoId:    todoId,
+		AuthorId:  &userId,
+		Body:      strings.TrimSpace(commentForm.Body),
+		CreatedOn: now,
+		UpdatedOn: now,
+	}
+	m.comments[comment.Id] = comment
+	return m.withAuthor(comment), nil
+}
+
+func (m *MemoryStore) GetComments(ctx context.Context, todoId int, afterId int, limit int) ([]model.Comment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var comments = make([]model.Comment, 0)
+	for _, comment := range m.comments {
+		if comment.TodoId == todoId && comment.Id > afterId {
+			comments = append(comments, *m.withAuthor(comment))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].Id < comments[j].Id
+	})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+func (m *MemoryStore) GetCommentBy(ctx context.Context, todoId int, commentId int) (*model.Comment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	comment, ok := m.comments[commentId]
+	if !ok || comment.TodoId != todoId {
+		return &model.Comment{}, ErrNotFound
+	}
+	return m.withAuthor(comment), nil
+}
+
+func (m *MemoryStore) UpdateCommentBy(ctx context.Context, commentId int, commentForm request.CommentForm) (*model.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	comment, ok := m.comments[commentId]
+	if !ok {
+		return &model.Comment{}, ErrNotFound
+	}
+	comment.Body = strings.TrimSpace(commentForm.Body)
+	comment.UpdatedOn = time.Now()
+	m.comments[commentId] = comment
+	return m.withAuthor(comment), nil
+}
+
+func (m *MemoryStore) RemoveCommentBy(ctx context.Context, commentId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.comments[commentId]; !ok {
+		return ErrNotFound
+	}
+	delete(m.comments, commentId)
+	return nil
+}
+
+// withAuthor fills in the user name of the author. Callers must hold the lock.
+func (m *MemoryStore) withAuthor(comment model.Comment) *model.Comment {
+	if comment.AuthorId != nil {
+		comment.AuthorName = m.accounts[*comment.AuthorId].UserName
+	}
+	return &comment
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"time"
+)
+
+func (m *MemoryStore) AddTodoEvent(ctx context.Context, todoId int, actorId int, action model.TodoAction, before interface{}, after interface{}) error {
+	beforeData, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[todoId]; !ok {
+		return ErrNotFound
+	}
+	m.lastEventId++
+	m.events = append(m.events, model.TodoEvent{
+		Id:        m.lastEventId,
+		TodoId:    todoId,
+		ActorId:   &actorId,
+		Action:    action,
+		Before:    beforeData,
+		After:     afterData,
+		CreatedOn: time.Now(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) GetTodoHistory(ctx context.Context, todoId int, beforeId int, limit int) ([]model.TodoEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latestEvents(beforeId, limit, func(event model.TodoEvent) bool {
+		return event.TodoId == todoId
+	}), nil
+}
+
+func (m *MemoryStore) GetActivityFeed(ctx context.Context, userId int, beforeId int, limit int) ([]model.TodoEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var linked = make(map[int]bool)
+	for _, link := range m.accountItems {
+		if link.accountId == userId {
+			linked[link.itemId] = true
+		}
+	}
+	return m.latestEvents(beforeId, limit, func(event model.TodoEvent) bool {
+		return linked[event.TodoId]
+	}), nil
+}
+
+// latestEvents walks events newest first. Callers must hold the lock.
+func (m *MemoryStore) latestEvents(beforeId int, limit int, matches func(event model.TodoEvent) bool) []model.TodoEvent {
+	var events = make([]model.TodoEvent, 0)
+	for i := len(m.events) - 1; i >= 0 && len(events) < limit; i-- {
+		var event = m.events[i]
+		if (beforeId != 0 && event.Id >= beforeId) || !matches(event) {
+			continue
+		}
+		if event.ActorId != nil {
+			event.ActorName = m.accounts[*event.ActorId].UserName
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func (m *MemoryStore) GetLastTodoEvent(ctx context.Context, todoId int, actions []model.TodoAction, window time.Duration) (*model.TodoEvent, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var allowed = make(map[model.TodoAction]bool, len(actions))
+	for _, action := range actions {
+		allowed[action] = true
+	}
+	var events = m.latestEvents(0, 1, func(event model.TodoEvent) bool {
+		return event.TodoId == todoId && allowed[event.Action] && !event.Undone
+	})
+	if len(events) == 0 {
+		return &model.TodoEvent{}, false, ErrNotFound
+	}
+	return &events[0], time.Since(events[0].CreatedOn) < window, nil
+}
+
+func (m *MemoryStore) MarkTodoEventUndone(ctx context.Context, eventId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.events {
+		if m.events[i].Id != eventId {
+			continue
+		}
+		if m.events[i].Undone {
+			return ErrAlreadyUndone
+		}
+		m.events[i].Undone = true
+		return nil
+	}
+	return ErrNotFound
+}
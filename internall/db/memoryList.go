@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) GetListsBy(ctx context.Context, userId int, includeArchived bool) ([]model.List, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var lists = make([]model.List, 0)
+	for _, list := range m.lists {
+		if list.AccountId == userId && (includeArchived || !list.Archived) {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Id < lists[j].Id
+	})
+	return lists, nil
+}
+
+func (m *MemoryStore) CreateListFor(ctx context.Context, userId int, listForm request.ListForm) (*model.List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[userId]; !ok {
+		return &model.List{}, ErrNotFound
+	}
+	m.lastListId++
+	var now = time.Now()
+	var list = model.List{
+		Id:        m.lastListId,
+		AccountId: userId,
+		Name:      listForm.Name,
+		Colour:    listForm.Colour,
+		Archived:  listForm.Archived,
+		CreatedOn: now,
+		UpdatedOn: now,
+	}
+	m.lists[list.Id] = list
+	return &list, nil
+}
+
+func (m *MemoryStore) GetListBy(ctx context.Context, listId int) (*model.List, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list, ok := m.lists[listId]
+	if !ok {
+		return &model.List{}, ErrNotFound
+	}
+	return &list, nil
+}
+
+func (m *MemoryStore) UpdateListBy(ctx context.Context, listId int, listForm request.ListForm) (*model.List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list, ok := m.lists[listId]
+	if !ok {
+		return &model.List{}, ErrNotFound
+	}
+	list.Name = listForm.Name
+	list.Colour = listForm.Colour
+	list.Archived = listForm.Archived
+	list.UpdatedOn = time.Now()
+	m.lists[listId] = list
+	return &list, nil
+}
+
+func (m *MemoryStore) RemoveListBy(ctx context.Context, listId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.lists, listId)
+	delete(m.workflows, listId)
+	for id, todo := range m.items {
+		if todo.ListId != nil && *todo.ListId == listId {
+			todo.ListId = nil
+			todo.Status = m.fitStatus(todo)
+			m.items[id] = todo
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetTodosByList(ctx context.Context, userId int, listId int) ([]model.Todo, error) {
+	todos, err := m.GetTodosBy(ctx, userId)
+	if err != nil {
+		return todos, err
+	}
+	var listTodos = make([]model.Todo, 0)
+	for _, todo := range todos {
+		if todo.ListId != nil && *todo.ListId == listId {
+			listTodos = append(listTodos, todo)
+		}
+	}
+	return listTodos, nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"time"
+)
+
+func (m *MemoryStore) AddNotification(ctx context.Context, accountId int, kind model.NotificationKind, todoId int, actorId int, data interface{}) error {
+	payload, err := marshalSnapshot(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[accountId]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.items[todoId]; !ok {
+		return ErrNotFound
+	}
+	m.addNotification(accountId, kind, todoId, &actorId, payload)
+	return nil
+}
+
+func (m *MemoryStore) GetNotifications(ctx context.Context, accountId int, beforeId int, limit int, unreadOnly bool) ([]model.Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var notifications = make([]model.Notification, 0)
+	for i := len(m.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		var notification = m.notifications[i]
+		if notification.AccountId != accountId || (beforeId != 0 && notification.Id >= beforeId) ||
+			(unreadOnly && notification.ReadAt != nil) {
+			continue
+		}
+		notifications = append(notifications, m.withActor(notification))
+	}
+	return notifications, nil
+}
+
+func (m *MemoryStore) CountUnreadNotifications(ctx context.Context, accountId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var count int
+	for _, notification := range m.notifications {
+		if notification.AccountId == accountId && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStore) MarkNotificationRead(ctx context.Context, accountId int, notificationId int) (*model.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, notification := range m.notifications {
+		if notification.Id != notificationId || notification.AccountId != accountId {
+			continue
+		}
+		if notification.ReadAt == nil {
+			var now = time.Now()
+			m.notifications[i].ReadAt = &now
+		}
+		notification = m.withActor(m.notifications[i])
+		return &notification, nil
+	}
+	return &model.Notification{}, ErrNotFound
+}
+
+func (m *MemoryStore) MarkAllNotificationsRead(ctx context.Context, accountId int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var now = time.Now()
+	var marked int64
+	for i, notification := range m.notifications {
+		if notification.AccountId == accountId && notification.ReadAt == nil {
+			m.notifications[i].ReadAt = &now
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (m *MemoryStore) SendDueReminders(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sent int64
+	for id, todo := range m.items {
+		if todo.RemindAt == nil || todo.RemindAt.After(now) || todo.Closed || todo.DeletedAt != nil {
+			continue
+		}
+		if reminded, ok := m.reminded[id]; ok && reminded.Equal(*todo.RemindAt) {
+			continue
+		}
+		m.reminded[id] = *todo.RemindAt
+		payload, err := marshalSnapshot(model.TodoReminder{
+			Title:    todo.Title,
+			DueAt:    todo.DueAt,
+			RemindAt: *todo.RemindAt,
+		})
+		if err != nil {
+			return sent, err
+		}
+		for _, link := range m.accountItems {
+			if link.itemId == id {
+				m.addNotification(link.accountId, model.NotificationReminder, id, nil, payload)
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// addNotification appends a notification. Callers must hold the lock.
+func (m *MemoryStore) addNotification(accountId int, kind model.NotificationKind, todoId int, actorId *int, data []byte) {
+	m.lastNotificationId++
+	m.notifications = append(m.notifications, model.Notification{
+		Id:        m.lastNotificationId,
+		AccountId: accountId,
+		Kind:      kind,
+		TodoId:    &todoId,
+		ActorId:   actorId,
+		Data:      data,
+		CreatedOn: time.Now(),
+	})
+}
+
+// withActor fills in the user name of the actor. Callers must hold the lock.
+func (m *MemoryStore) withActor(notification model.Notification) model.Notification {
+	if notification.ActorId != nil {
+		notification.ActorName = m.accounts[*notification.ActorId].UserName
+	}
+	return notification
+}
@@ -0,0 +1,39 @@
+package db
+
+import "context"
+
+func (m *MemoryStore) MoveTodo(ctx context.Context, userId int, todoId int, anchorId int, after bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var positions = make([]itemPosition, 0)
+	var indexes = make(map[int]int)
+	for i, link := range m.accountItems {
+		if link.accountId == userId {
+			positions = append(positions, itemPosition{itemId: link.itemId, position: link.position})
+			indexes[link.itemId] = i
+		}
+	}
+	changes, err := placeTodo(positions, todoId, anchorId, after)
+	if err != nil {
+		return 0, err
+	}
+	var position int64
+	for _, change := range changes {
+		m.accountItems[indexes[change.itemId]].position = change.position
+		if change.itemId == todoId {
+			position = change.position
+		}
+	}
+	return position, nil
+}
+
+// nextPosition is the position after the last todo of the account. Callers must hold the lock.
+func (m *MemoryStore) nextPosition(accountId int) int64 {
+	var last int64
+	for _, link := range m.accountItems {
+		if link.accountId == accountId && link.position > last {
+			last = link.position
+		}
+	}
+	return last + positionGap
+}
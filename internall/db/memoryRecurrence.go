@@ -0,0 +1,50 @@
+package db
+
+import (
+	"github.com/IosifSuzuki/todo/internall/model"
+	"time"
+)
+
+// createNextOccurrence is insertNextOccurrence for the memory store. Callers must hold the lock.
+func (m *MemoryStore) createNextOccurrence(todo model.Todo) *model.Todo {
+	nextDue, seriesId, recurrence, ok := nextOccurrenceOf(todo)
+	if !ok {
+		return nil
+	}
+	for _, item := range m.items {
+		if item.SeriesId != nil && *item.SeriesId == seriesId && item.Occurrence == todo.Occurrence+1 {
+			return nil
+		}
+	}
+	m.lastItemId++
+	var now = time.Now()
+	var next = model.Todo{
+		Id:          m.lastItemId,
+		Title:       todo.Title,
+		Description: todo.Description,
+		CreatedOn:   now,
+		UpdatedOn:   now,
+		ListId:      todo.ListId,
+		Status:      m.workflowOf(todo.ListId).Initial(),
+		DueAt:       &nextDue,
+		Priority:    todo.Priority,
+		RemindAt:    shiftedReminder(todo, nextDue),
+		Recurrence:  recurrence,
+		SeriesId:    &seriesId,
+		Occurrence:  todo.Occurrence + 1,
+		AssigneeId:  todo.AssigneeId,
+		Version:     1,
+	}
+	m.items[next.Id] = next
+	for _, link := range m.accountItems {
+		if link.itemId == todo.Id {
+			m.accountItems = append(m.accountItems, accountItem{
+				accountId: link.accountId,
+				itemId:    next.Id,
+				role:      link.role,
+				position:  m.nextPosition(link.accountId),
+			})
+		}
+	}
+	return &next
+}
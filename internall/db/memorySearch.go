@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchTodos matches todos containing every token of the text in their title or
+// description. Title hits weigh more than description hits, like the postgres ranking.
+func (m *MemoryStore) SearchTodos(ctx context.Context, userId int, text string, limit int) ([]model.TodoSearchResult, error) {
+	var results = make([]model.TodoSearchResult, 0)
+	var tokens = strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) == 0 {
+		return results, nil
+	}
+	var quoted = make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		quoted = append(quoted, regexp.QuoteMeta(token))
+	}
+	var pattern = regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	todos, err := m.GetTodosBy(ctx, userId)
+	if err != nil {
+		return results, err
+	}
+	for _, todo := range todos {
+		var title, description = strings.ToLower(todo.Title), strings.ToLower(todo.Description)
+		var rank float32
+		for _, token := range tokens {
+			var titleHits, descriptionHits = strings.Count(title, token), strings.Count(description, token)
+			if titleHits+descriptionHits == 0 {
+				rank = 0
+				break
+			}
+			rank += float32(titleHits) + 0.4*float32(descriptionHits)
+		}
+		if rank == 0 {
+			continue
+		}
+		results = append(results, model.TodoSearchResult{
+			Todo:               todo,
+			Rank:               rank,
+			TitleSnippet:       markSnippet(pattern.ReplaceAllString(todo.Title, snippetStart+"$0"+snippetStop)),
+			DescriptionSnippet: markSnippet(pattern.ReplaceAllString(todo.Description, snippetStart+"$0"+snippetStop)),
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Todo.Id < results[j].Todo.Id
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
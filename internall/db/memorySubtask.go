@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) CreateSubtaskFor(ctx context.Context, parentId int, todoForm request.TodoForm) (*model.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[parentId]; !ok {
+		return &model.Todo{}, ErrNotFound
+	}
+	m.lastItemId++
+	var now = time.Now()
+	var todo = model.Todo{
+		Id:          m.lastItemId,
+		Title:       todoForm.Title,
+		Description: todoForm.Description,
+		CreatedOn:   now,
+		UpdatedOn:   now,
+		ListId:      todoForm.ListId,
+		Status:      m.workflowOf(todoForm.ListId).Initial(),
+		DueAt:       utcTime(todoForm.DueAt),
+		Priority:    todoForm.EffectivePriority(),
+		RemindAt:    utcTime(todoForm.RemindAt),
+		Recurrence:  todoForm.Recurrence,
+		Occurrence:  1,
+		ParentId:    &parentId,
+		Version:     1,
+	}
+	m.positions[todo.Id] = m.nextSubtaskPosition(parentId)
+	m.items[todo.Id] = todo
+	for _, link := range m.accountItems {
+		if link.itemId == parentId {
+			m.accountItems = append(m.accountItems, accountItem{
+				accountId: link.accountId,
+				itemId:    todo.Id,
+				role:      link.role,
+				position:  m.nextPosition(link.accountId),
+			})
+		}
+	}
+	return &todo, nil
+}
+
+func (m *MemoryStore) GetSubtasks(ctx context.Context, parentId int) ([]model.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var subtasks = make([]model.Todo, 0)
+	for _, todo := range m.items {
+		if todo.ParentId != nil && *todo.ParentId == parentId && todo.DeletedAt == nil {
+			subtasks = append(subtasks, m.withProgress(todo))
+		}
+	}
+	sort.Slice(subtasks, func(i, j int) bool {
+		var a, b = m.positions[subtasks[i].Id], m.positions[subtasks[j].Id]
+		if a != b {
+			return a < b
+		}
+		return subtasks[i].Id < subtasks[j].Id
+	})
+	return subtasks, nil
+}
+
+func (m *MemoryStore) ReorderSubtasks(ctx context.Context, parentId int, subtaskIds []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var currentIds = make([]int, 0)
+	for id, todo := range m.items {
+		if todo.ParentId != nil && *todo.ParentId == parentId && todo.DeletedAt == nil {
+			currentIds = append(currentIds, id)
+		}
+	}
+	if !sameIds(currentIds, subtaskIds) {
+		return ErrInvalidOrder
+	}
+	for position, id := range subtaskIds {
+		m.positions[id] = position + 1
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetTodoParent(ctx context.Context, todoId int, parentId *int, version *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, ok := m.items[todoId]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != nil && todo.Version != *version {
+		return ErrVersionMismatch
+	}
+	for ancestorId := parentId; ancestorId != nil; {
+		if *ancestorId == todoId {
+			return ErrCycle
+		}
+		ancestor, ok := m.items[*ancestorId]
+		if !ok {
+			return ErrNotFound
+		}
+		ancestorId = ancestor.ParentId
+	}
+	todo.ParentId = parentId
+	todo.Version++
+	m.items[todoId] = todo
+	if parentId != nil {
+		m.positions[todoId] = m.nextSubtaskPosition(*parentId)
+	} else {
+		delete(m.positions, todoId)
+	}
+	return nil
+}
+
+func (m *MemoryStore) nextSubtaskPosition(parentId int) int {
+	var position int
+	for id, todo := range m.items {
+		if todo.ParentId != nil && *todo.ParentId == parentId && m.positions[id] > position {
+			position = m.positions[id]
+		}
+	}
+	return position + 1
+}
+
+// withProgress fills the share of closed direct subtasks like the postgres progress column.
+func (m *MemoryStore) withProgress(todo model.Todo) model.Todo {
+	var total, closed int
+	for _, item := range m.items {
+		if item.ParentId != nil && *item.ParentId == todo.Id && item.DeletedAt == nil {
+			total++
+			if item.Closed {
+				closed++
+			}
+		}
+	}
+	todo.Progress = nil
+	if total > 0 {
+		var progress = int(float64(100*closed)/float64(total) + 0.5)
+		todo.Progress = &progress
+	}
+	return todo
+}
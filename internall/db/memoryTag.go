@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStore) GetTagsBy(ctx context.Context, userId int) ([]model.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tags = make([]model.Tag, 0)
+	for _, tag := range m.tags {
+		if tag.AccountId == userId {
+			tags = append(tags, tag)
+		}
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+func (m *MemoryStore) CreateTagFor(ctx context.Context, userId int, tagForm request.TagForm) (*model.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[userId]; !ok {
+		return &model.Tag{}, ErrNotFound
+	}
+	var name = strings.TrimSpace(tagForm.Name)
+	if m.tagNameTaken(userId, 0, name) {
+		return &model.Tag{}, ErrTagExists
+	}
+	m.lastTagId++
+	var tag = model.Tag{
+		Id:        m.lastTagId,
+		AccountId: userId,
+		Name:      name,
+		CreatedOn: time.Now(),
+	}
+	m.tags[tag.Id] = tag
+	return &tag, nil
+}
+
+func (m *MemoryStore) GetTagBy(ctx context.Context, tagId int) (*model.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tag, ok := m.tags[tagId]
+	if !ok {
+		return &model.Tag{}, ErrNotFound
+	}
+	return &tag, nil
+}
+
+func (m *MemoryStore) UpdateTagBy(ctx context.Context, tagId int, tagForm request.TagForm) (*model.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tag, ok := m.tags[tagId]
+	if !ok {
+		return &model.Tag{}, ErrNotFound
+	}
+	var name = strings.TrimSpace(tagForm.Name)
+	if m.tagNameTaken(tag.AccountId, tagId, name) {
+		return &model.Tag{}, ErrTagExists
+	}
+	tag.Name = name
+	m.tags[tagId] = tag
+	return &tag, nil
+}
+
+func (m *MemoryStore) RemoveTagBy(ctx context.Context, tagId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tags, tagId)
+	for link := range m.itemTags {
+		if link.tagId == tagId {
+			delete(m.itemTags, link)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetTodoTags(ctx context.Context, userId int, todoId int) ([]model.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tags = make([]model.Tag, 0)
+	for link := range m.itemTags {
+		if tag := m.tags[link.tagId]; link.itemId == todoId && tag.AccountId == userId {
+			tags = append(tags, tag)
+		}
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+func (m *MemoryStore) AttachTag(ctx context.Context, todoId int, tagId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.todoAt(todoId, nil); err != nil {
+		return err
+	}
+	if _, ok := m.tags[tagId]; !ok {
+		return ErrNotFound
+	}
+	m.itemTags[itemTag{itemId: todoId, tagId: tagId}] = true
+	return nil
+}
+
+func (m *MemoryStore) DetachTag(ctx context.Context, todoId int, tagId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.itemTags, itemTag{itemId: todoId, tagId: tagId})
+	return nil
+}
+
+// tagNamesBy returns lower cased names of the account's tags by todo id. Callers must hold the lock.
+func (m *MemoryStore) tagNamesBy(userId int) map[int]map[string]bool {
+	var names = make(map[int]map[string]bool)
+	for link := range m.itemTags {
+		tag := m.tags[link.tagId]
+		if tag.AccountId != userId {
+			continue
+		}
+		if names[link.itemId] == nil {
+			names[link.itemId] = make(map[string]bool)
+		}
+		names[link.itemId][strings.ToLower(tag.Name)] = true
+	}
+	return names
+}
+
+// tagNameTaken mirrors the unique index on (account_id, lower(name)). Callers must hold the lock.
+func (m *MemoryStore) tagNameTaken(userId int, tagId int, name string) bool {
+	for _, tag := range m.tags {
+		if tag.AccountId == userId && tag.Id != tagId && strings.EqualFold(tag.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortTags(tags []model.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+}
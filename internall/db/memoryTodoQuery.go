@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStore) GetTodosPage(ctx context.Context, userId int, todoQuery request.TodoQuery) (*model.TodoPage, error) {
+	todos, err := m.GetTodosBy(ctx, userId)
+	if err != nil {
+		return &model.TodoPage{Todos: make([]model.Todo, 0)}, err
+	}
+	m.mu.RLock()
+	var tagNames = m.tagNamesBy(userId)
+	m.mu.RUnlock()
+	var matched = make([]model.Todo, 0, len(todos))
+	for _, todo := range todos {
+		if matchesTodoQuery(todo, todoQuery) && matchesTags(tagNames[todo.Id], todoQuery.Tags) {
+			matched = append(matched, todo)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return compareTodos(matched[i], matched[j], todoQuery) < 0
+	})
+	if cursor := todoQuery.Cursor; cursor != nil {
+		var position = sort.Search(len(matched), func(i int) bool {
+			return compareToCursor(matched[i], cursor, todoQuery) > 0
+		})
+		matched = matched[position:]
+	}
+	if len(matched) > todoQuery.Limit+1 {
+		matched = matched[:todoQuery.Limit+1]
+	}
+	var page = &model.TodoPage{Todos: matched}
+	paginate(page, todoQuery)
+	return page, nil
+}
+
+func matchesTodoQuery(todo model.Todo, todoQuery request.TodoQuery) bool {
+	if todoQuery.Closed != nil && todo.Closed != *todoQuery.Closed {
+		return false
+	}
+	if todoQuery.CreatedAfter != nil && todo.CreatedOn.Before(*todoQuery.CreatedAfter) {
+		return false
+	}
+	if todoQuery.CreatedBefore != nil && !todo.CreatedOn.Before(*todoQuery.CreatedBefore) {
+		return false
+	}
+	if todoQuery.UpdatedAfter != nil && todo.UpdatedOn.Before(*todoQuery.UpdatedAfter) {
+		return false
+	}
+	if todoQuery.UpdatedBefore != nil && !todo.UpdatedOn.Before(*todoQuery.UpdatedBefore) {
+		return false
+	}
+	if len(todoQuery.Text) != 0 {
+		var text = strings.ToLower(todoQuery.Text)
+		if !strings.Contains(strings.ToLower(todo.Title), text) &&
+			!strings.Contains(strings.ToLower(todo.Description), text) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTags(names map[string]bool, tags [][]string) bool {
+	for _, alternatives := range tags {
+		var found = false
+		for _, name := range alternatives {
+			found = found || names[name]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compareTodos orders todos by the requested field with the id as tie breaker.
+func compareTodos(a model.Todo, b model.Todo, todoQuery request.TodoQuery) int {
+	var result int
+	switch todoQuery.SortField {
+	case request.SortByTitle:
+		result = strings.Compare(a.Title, b.Title)
+	case request.SortByCreatedOn:
+		result = compareTimes(a.CreatedOn, b.CreatedOn)
+	case request.SortByUpdatedOn:
+		result = compareTimes(a.UpdatedOn, b.UpdatedOn)
+	case request.SortByPosition:
+		result = compareInts(int(a.Position), int(b.Position))
+	}
+	if result == 0 {
+		result = compareInts(a.Id, b.Id)
+	}
+	if todoQuery.Descending {
+		return -result
+	}
+	return result
+}
+
+func compareToCursor(todo model.Todo, cursor *request.TodoCursor, todoQuery request.TodoQuery) int {
+	var anchor = model.Todo{Id: cursor.Id, Title: cursor.Value}
+	if value, err := cursor.TimeValue(); err == nil {
+		anchor.CreatedOn = value
+		anchor.UpdatedOn = value
+	}
+	if value, err := cursor.PositionValue(); err == nil {
+		anchor.Position = value
+	}
+	return compareTodos(todo, anchor, todoQuery)
+}
+
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (m *MemoryStore) GetOpenTodosDue(ctx context.Context, userId int, from *time.Time, to time.Time) ([]model.Todo, error) {
+	todos, err := m.GetTodosBy(ctx, userId)
+	if err != nil {
+		return todos, err
+	}
+	var due = make([]model.Todo, 0)
+	for _, todo := range todos {
+		if todo.Closed || todo.DueAt == nil || !todo.DueAt.Before(to) {
+			continue
+		}
+		if from != nil && todo.DueAt.Before(*from) {
+			continue
+		}
+		due = append(due, todo)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if !due[i].DueAt.Equal(*due[j].DueAt) {
+			return due[i].DueAt.Before(*due[j].DueAt)
+		}
+		return due[i].Id < due[j].Id
+	})
+	return due, nil
+}
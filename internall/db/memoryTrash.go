@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) GetTrashedTodos(ctx context.Context, userId int) ([]model.Todo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var todos = make([]model.Todo, 0)
+	for _, link := range m.accountItems {
+		if link.accountId != userId || link.role != model.RoleOwner {
+			continue
+		}
+		if todo, ok := m.items[link.itemId]; ok && todo.DeletedAt != nil {
+			todos = append(todos, m.withProgress(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].DeletedAt.Equal(*todos[j].DeletedAt) {
+			return todos[i].DeletedAt.After(*todos[j].DeletedAt)
+		}
+		return todos[i].Id < todos[j].Id
+	})
+	return todos, nil
+}
+
+func (m *MemoryStore) GetTrashedTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error) {
+	return m.todoRole(userId, todoId, true)
+}
+
+func (m *MemoryStore) RestoreTodoBy(ctx context.Context, todoId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, ok := m.items[todoId]
+	if !ok || todo.DeletedAt == nil {
+		return ErrNotFound
+	}
+	var deletedAt = *todo.DeletedAt
+	var subtree = map[int]bool{todoId: true}
+	for found := true; found; {
+		found = false
+		for id, item := range m.items {
+			if !subtree[id] && item.DeletedAt != nil && item.DeletedAt.Equal(deletedAt) &&
+				item.ParentId != nil && subtree[*item.ParentId] {
+				subtree[id] = true
+				found = true
+			}
+		}
+	}
+	for id := range subtree {
+		item := m.items[id]
+		item.DeletedAt = nil
+		item.Version++
+		m.items[id] = item
+	}
+	if todo.ParentId != nil && m.items[*todo.ParentId].DeletedAt != nil {
+		todo = m.items[todoId]
+		todo.ParentId = nil
+		m.items[todoId] = todo
+		delete(m.positions, todoId)
+	}
+	return nil
+}
+
+func (m *MemoryStore) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var removed = make(map[int]bool)
+	for id, todo := range m.items {
+		if todo.DeletedAt != nil && todo.DeletedAt.Before(before) {
+			removed[id] = true
+		}
+	}
+	m.removeItems(removed)
+	return int64(len(removed)), nil
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"testing"
+	"time"
+)
+
+func newTrashStore(t *testing.T) (*MemoryStore, int) {
+	t.Helper()
+	var store = NewMemoryStore()
+	account, err := store.CreateAccount(context.Background(), request.RegistrationForm{
+		UserName: "alice",
+		Password: "password",
+		Email:    "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("cannot create account: %v", err)
+	}
+	return store, account.Id
+}
+
+func createTodo(t *testing.T, store *MemoryStore, userId int, parentId *int, title string) *model.Todo {
+	t.Helper()
+	var todo *model.Todo
+	var err error
+	if parentId == nil {
+		todo, err = store.CreteTodoFor(context.Background(), userId, request.TodoForm{Title: title})
+	} else {
+		todo, err = store.CreateSubtaskFor(context.Background(), *parentId, request.TodoForm{Title: title})
+	}
+	if err != nil {
+		t.Fatalf("cannot create todo %s: %v", title, err)
+	}
+	return todo
+}
+
+func expectVisible(t *testing.T, store *MemoryStore, visible bool, todos ...*model.Todo) {
+	t.Helper()
+	for _, todo := range todos {
+		_, err := store.GetTodoBy(context.Background(), todo.Id)
+		if visible && err != nil {
+			t.Fatalf("expected %s outside of the trash, got %v", todo.Title, err)
+		}
+		if !visible && !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected %s in the trash, got %v", todo.Title, err)
+		}
+	}
+}
+
+func TestTrashAndRestoreSubtree(t *testing.T) {
+	var store, alice = newTrashStore(t)
+	var ctx = context.Background()
+	var trip = createTodo(t, store, alice, nil, "Trip")
+	var packing = createTodo(t, store, alice, &trip.Id, "Packing")
+	var socks = createTodo(t, store, alice, &packing.Id, "Socks")
+	var tickets = createTodo(t, store, alice, &trip.Id, "Tickets")
+	if err := store.RemoveTodoBy(ctx, tickets.Id, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveTodoBy(ctx, trip.Id, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectVisible(t, store, false, trip, packing, socks, tickets)
+	trashed, err := store.GetTrashedTodos(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 4 {
+		t.Fatalf("expected the whole subtree in the trash, got %+v", trashed)
+	}
+	if err := store.RestoreTodoBy(ctx, trip.Id); err != nil {
+		t.Fatal(err)
+	}
+	expectVisible(t, store, true, trip, packing, socks)
+	expectVisible(t, store, false, tickets)
+	restored, err := store.GetTodoBy(ctx, socks.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ParentId == nil || *restored.ParentId != packing.Id {
+		t.Fatalf("expected socks to stay a subtask of packing, got %+v", restored)
+	}
+	if err := store.RestoreTodoBy(ctx, trip.Id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a second restore to find nothing, got %v", err)
+	}
+}
+
+func TestRestoreSubtaskOfTrashedParent(t *testing.T) {
+	var store, alice = newTrashStore(t)
+	var ctx = context.Background()
+	var trip = createTodo(t, store, alice, nil, "Trip")
+	var packing = createTodo(t, store, alice, &trip.Id, "Packing")
+	var socks = createTodo(t, store, alice, &packing.Id, "Socks")
+	if err := store.RemoveTodoBy(ctx, trip.Id, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RestoreTodoBy(ctx, packing.Id); err != nil {
+		t.Fatal(err)
+	}
+	expectVisible(t, store, true, packing, socks)
+	expectVisible(t, store, false, trip)
+	restored, err := store.GetTodoBy(ctx, packing.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ParentId != nil {
+		t.Fatalf("expected packing to become a top level todo, got parent %d", *restored.ParentId)
+	}
+	todos, err := store.GetTodosBy(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("expected packing and socks in my todos, got %+v", todos)
+	}
+}
+
+func TestPurgeTrashRemovesOldTodosAndTheirAttachments(t *testing.T) {
+	var store, alice = newTrashStore(t)
+	var ctx = context.Background()
+	var trip = createTodo(t, store, alice, nil, "Trip")
+	var packing = createTodo(t, store, alice, &trip.Id, "Packing")
+	var groceries = createTodo(t, store, alice, nil, "Groceries")
+	for _, todo := range []*model.Todo{packing, groceries} {
+		var attachment = &model.Attachment{TodoId: todo.Id, AccountId: alice, Size: 10, StorageKey: todo.Title}
+		if err := store.CreateAttachment(ctx, attachment, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RemoveTodoBy(ctx, trip.Id, nil); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	if err != nil || removed != 0 {
+		t.Fatalf("expected todos trashed within the retention to stay, removed %d: %v", removed, err)
+	}
+	removed, err = store.PurgeTrash(ctx, time.Now().Add(time.Hour))
+	if err != nil || removed != 2 {
+		t.Fatalf("expected trip and packing to be purged, removed %d: %v", removed, err)
+	}
+	if err := store.RestoreTodoBy(ctx, trip.Id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a purged todo to be gone, got %v", err)
+	}
+	keys, err := store.PurgeDetachedAttachments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != packing.Title {
+		t.Fatalf("expected only the attachment of packing to be purged, got %v", keys)
+	}
+	usage, err := store.GetAttachmentUsage(ctx, alice)
+	if err != nil || usage != 10 {
+		t.Fatalf("expected the attachment of groceries to stay, usage %d: %v", usage, err)
+	}
+	expectVisible(t, store, true, groceries)
+}
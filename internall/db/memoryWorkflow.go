@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"time"
+)
+
+func (m *MemoryStore) GetWorkflow(ctx context.Context, listId *int) (*model.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var workflow = m.workflowOf(listId)
+	return &workflow, nil
+}
+
+func (m *MemoryStore) SetWorkflow(ctx context.Context, listId int, workflow model.Workflow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lists[listId]; !ok {
+		return ErrNotFound
+	}
+	m.workflows[listId] = workflow
+	for id, todo := range m.items {
+		if todo.ListId == nil || *todo.ListId != listId {
+			continue
+		}
+		var status = workflow.Fit(todo.Status, todo.Closed)
+		var state, _ = workflow.State(status)
+		if status == todo.Status && state.Terminal == todo.Closed {
+			continue
+		}
+		todo.Status = status
+		if state.Terminal != todo.Closed {
+			todo.Closed = state.Terminal
+			todo.ClosedAt = nil
+			todo.ClosedBy = nil
+			if todo.Closed {
+				var now = time.Now()
+				todo.ClosedAt = &now
+			}
+		}
+		todo.Version++
+		m.items[id] = todo
+	}
+	return nil
+}
+
+func (m *MemoryStore) TransitionTodo(ctx context.Context, todoId int, userId int, status string, version *int) (*model.Todo, *model.Todo, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	todo, err := m.todoAt(todoId, version)
+	if err != nil {
+		return &model.Todo{}, nil, false, err
+	}
+	var workflow = m.workflowOf(todo.ListId)
+	state, ok := workflow.State(status)
+	if !ok {
+		return &model.Todo{}, nil, false, ErrUnknownState
+	}
+	if todo.Status == status {
+		todo = m.withProgress(todo)
+		return &todo, nil, false, nil
+	}
+	if !workflow.Allows(todo.Status, status) {
+		return &model.Todo{}, nil, false, ErrInvalidTransition
+	}
+	var wasClosed = todo.Closed
+	if state.Terminal && !todo.Closed {
+		var now = time.Now()
+		todo.ClosedAt = &now
+		todo.ClosedBy = &userId
+	} else if !state.Terminal {
+		todo.ClosedAt = nil
+		todo.ClosedBy = nil
+	}
+	todo.Closed = state.Terminal
+	todo.Status = status
+	todo.Version++
+	m.items[todoId] = todo
+	var next *model.Todo
+	if todo.Closed && !wasClosed {
+		next = m.createNextOccurrence(todo)
+	}
+	todo = m.withProgress(todo)
+	return &todo, next, true, nil
+}
+
+// workflowOf returns the workflow of the list or the default one. Callers must hold the lock.
+func (m *MemoryStore) workflowOf(listId *int) model.Workflow {
+	if listId != nil {
+		if workflow, ok := m.workflows[*listId]; ok {
+			return workflow
+		}
+	}
+	return model.DefaultWorkflow()
+}
+
+// fitStatus is model.Workflow.Fit for the workflow of the todo's list. Callers must hold the lock.
+func (m *MemoryStore) fitStatus(todo model.Todo) string {
+	var workflow = m.workflowOf(todo.ListId)
+	return workflow.Fit(todo.Status, todo.Closed)
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+const notificationColumns = "notification.id, notification.account_id, notification.kind, notification.item_id, " +
+	"notification.actor_id, coalesce(account.username, ''), notification.data, notification.read_at, notification.created_on"
+
+func scanNotification(row pgx.Row, notification *model.Notification) error {
+	var data []byte
+	if err := row.Scan(
+		&notification.Id,
+		&notification.AccountId,
+		&notification.Kind,
+		&notification.TodoId,
+		&notification.ActorId,
+		&notification.ActorName,
+		&data,
+		&notification.ReadAt,
+		&notification.CreatedOn,
+	); err != nil {
+		return err
+	}
+	notification.Data = data
+	return nil
+}
+
+func (p *PostgresStore) AddNotification(ctx context.Context, accountId int, kind model.NotificationKind, todoId int, actorId int, data interface{}) error {
+	payload, err := marshalSnapshot(data)
+	if err != nil {
+		return err
+	}
+	_, err = p.connectionDB.Exec(ctx, "INSERT INTO notification (account_id, kind, item_id, actor_id, data) "+
+		"VALUES ($1, $2, $3, $4, $5)", accountId, kind, todoId, actorId, payload,
+	)
+	return err
+}
+
+// GetNotifications returns notifications of the account newest first, a beforeId of 0 starts from the latest one.
+func (p *PostgresStore) GetNotifications(ctx context.Context, accountId int, beforeId int, limit int, unreadOnly bool) ([]model.Notification, error) {
+	var notifications = make([]model.Notification, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+notificationColumns+" "+
+		"FROM notification LEFT JOIN account ON account.id = notification.actor_id "+
+		"WHERE notification.account_id = $1 AND ($2 = 0 OR notification.id < $2) AND (NOT $4 OR notification.read_at IS NULL) "+
+		"ORDER BY notification.id DESC LIMIT $3", accountId, beforeId, limit, unreadOnly,
+	)
+	if err != nil {
+		return notifications, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var notification = model.Notification{}
+		if err := scanNotification(rows, &notification); err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+func (p *PostgresStore) CountUnreadNotifications(ctx context.Context, accountId int) (int, error) {
+	var count int
+	err := p.connectionDB.QueryRow(ctx, "SELECT count(*) FROM notification WHERE account_id = $1 AND read_at IS NULL",
+		accountId).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead keeps the time a notification was first read, so marking it again changes nothing.
+func (p *PostgresStore) MarkNotificationRead(ctx context.Context, accountId int, notificationId int) (*model.Notification, error) {
+	var notification = &model.Notification{}
+	err := scanNotification(p.connectionDB.QueryRow(ctx, "WITH updated AS ("+
+		"UPDATE notification SET read_at = coalesce(read_at, current_timestamp) WHERE id = $1 AND account_id = $2 RETURNING *"+
+		") SELECT "+notificationColumns+" FROM updated AS notification LEFT JOIN account ON account.id = notification.actor_id",
+		notificationId, accountId,
+	), notification)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notification, ErrNotFound
+	}
+	return notification, err
+}
+
+func (p *PostgresStore) MarkAllNotificationsRead(ctx context.Context, accountId int) (int64, error) {
+	tag, err := p.connectionDB.Exec(ctx, "UPDATE notification SET read_at = current_timestamp "+
+		"WHERE account_id = $1 AND read_at IS NULL", accountId)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SendDueReminders marks reminders as sent by copying remind_at into reminded_at,
+// so moving the reminder of a todo makes it due again.
+func (p *PostgresStore) SendDueReminders(ctx context.Context, now time.Time) (int64, error) {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	rows, err := tx.Query(ctx, "UPDATE item SET reminded_at = remind_at "+
+		"WHERE remind_at <= $1 AND reminded_at IS DISTINCT FROM remind_at AND NOT closed AND deleted_at IS NULL "+
+		"RETURNING id, title, due_at, remind_at", now.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	var todoIds = make([]int, 0)
+	var reminders = make([]model.TodoReminder, 0)
+	for rows.Next() {
+		var todoId int
+		var reminder = model.TodoReminder{}
+		if err = rows.Scan(&todoId, &reminder.Title, &reminder.DueAt, &reminder.RemindAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		todoIds = append(todoIds, todoId)
+		reminders = append(reminders, reminder)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	var sent int64
+	for i, todoId := range todoIds {
+		var payload []byte
+		if payload, err = marshalSnapshot(reminders[i]); err != nil {
+			return 0, err
+		}
+		var tag pgconn.CommandTag
+		tag, err = tx.Exec(ctx, "INSERT INTO notification (account_id, kind, item_id, data) "+
+			"SELECT account_id, $2, item_id, $3 FROM account_item WHERE item_id = $1", todoId, model.NotificationReminder, payload,
+		)
+		if err != nil {
+			return 0, err
+		}
+		sent += tag.RowsAffected()
+	}
+	return sent, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+)
+
+// positionGap is the distance between neighbouring todos of a manual order, moves
+// take the middle between two neighbours and renumber the order once no gap is left.
+// Positions stay above zero.
+const positionGap int64 = 65536
+
+// nextPositionSQL selects the position after the last todo of the account in the given column.
+func nextPositionSQL(accountColumn string) string {
+	return fmt.Sprintf("(SELECT coalesce(max(positioned.position), 0) + %d FROM account_item AS positioned "+
+		"WHERE positioned.account_id = %s)", positionGap, accountColumn)
+}
+
+type itemPosition struct {
+	itemId   int
+	position int64
+}
+
+// placeTodo moves the todo right before or after the anchor inside the ordered
+// positions of an account and returns the positions which have to be written.
+func placeTodo(positions []itemPosition, todoId int, anchorId int, after bool) ([]itemPosition, error) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].position == positions[j].position {
+			return positions[i].itemId < positions[j].itemId
+		}
+		return positions[i].position < positions[j].position
+	})
+	var ordered = make([]itemPosition, 0, len(positions))
+	var found = false
+	for _, item := range positions {
+		if item.itemId == todoId {
+			found = true
+		} else {
+			ordered = append(ordered, item)
+		}
+	}
+	var index = -1
+	for i, item := range ordered {
+		if item.itemId == anchorId {
+			index = i
+		}
+	}
+	if !found || index < 0 {
+		return nil, ErrNotFound
+	}
+	if after {
+		index++
+	}
+	var lower, upper int64
+	switch {
+	case len(ordered) == 0:
+		lower, upper = 0, 2*positionGap
+	case index == 0:
+		lower, upper = 0, ordered[0].position
+	case index == len(ordered):
+		lower = ordered[index-1].position
+		upper = lower + 2*positionGap
+	default:
+		lower, upper = ordered[index-1].position, ordered[index].position
+	}
+	if upper-lower > 1 {
+		return []itemPosition{{itemId: todoId, position: lower + (upper-lower)/2}}, nil
+	}
+	ordered = append(ordered[:index], append([]itemPosition{{itemId: todoId}}, ordered[index:]...)...)
+	for i := range ordered {
+		ordered[i].position = int64(i+1) * positionGap
+	}
+	return ordered, nil
+}
+
+// MoveTodo places the todo right before or after the anchor in the manual order
+// of the account and returns its new position. Both todos must be linked to the account.
+func (p *PostgresStore) MoveTodo(ctx context.Context, userId int, todoId int, anchorId int, after bool) (int64, error) {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	rows, err := tx.Query(ctx, "SELECT item_id, position FROM account_item WHERE account_id = $1 FOR UPDATE", userId)
+	if err != nil {
+		return 0, err
+	}
+	var positions = make([]itemPosition, 0)
+	for rows.Next() {
+		var item itemPosition
+		if err = rows.Scan(&item.itemId, &item.position); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		positions = append(positions, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	changes, err := placeTodo(positions, todoId, anchorId, after)
+	if err != nil {
+		return 0, err
+	}
+	var position int64
+	for _, change := range changes {
+		_, err = tx.Exec(ctx, "UPDATE account_item SET position = $3 WHERE account_id = $1 AND item_id = $2",
+			userId, change.itemId, change.position)
+		if err != nil {
+			return 0, err
+		}
+		if change.itemId == todoId {
+			position = change.position
+		}
+	}
+	return position, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+// insertNextOccurrence generates the occurrence which follows the todo inside the transaction
+// which closed it. The new todo inherits fields and collaborators of the closed one. Nil is returned
+// when the todo is not a closed recurring todo, its series is over or the next occurrence exists already.
+func insertNextOccurrence(ctx context.Context, tx pgx.Tx, todo model.Todo) (*model.Todo, error) {
+	nextDue, seriesId, recurrence, ok := nextOccurrenceOf(todo)
+	if !ok {
+		return nil, nil
+	}
+	var nextId int
+	err := tx.QueryRow(ctx, "INSERT INTO item (title, description, closed, list_id, due_at, priority, remind_at, "+
+		"recurrence, series_id, occurrence, assignee_id, status) VALUES ($1, $2, false, $3, $4, $5, $6, $7, $8, $9, $10, "+
+		initialStateSQL("$3")+") "+
+		"ON CONFLICT (series_id, occurrence) DO NOTHING RETURNING id",
+		todo.Title, todo.Description, todo.ListId, nextDue, todo.Priority, shiftedReminder(todo, nextDue),
+		recurrence, seriesId, todo.Occurrence+1, todo.AssigneeId,
+	).Scan(&nextId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO account_item (account_id, item_id, role, position) "+
+		"SELECT account_id, $2, role, "+nextPositionSQL("account_item.account_id")+" "+
+		"FROM account_item WHERE item_id = $1", todo.Id, nextId)
+	if err != nil {
+		return nil, err
+	}
+	var next = &model.Todo{}
+	err = scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM item WHERE id = $1", nextId), next)
+	return next, err
+}
+
+// nextOccurrenceOf computes due date, series id and recurrence of the occurrence following the todo.
+func nextOccurrenceOf(todo model.Todo) (time.Time, int, *model.Recurrence, bool) {
+	if !todo.Closed || todo.Recurrence == nil || todo.DueAt == nil {
+		return time.Time{}, 0, nil, false
+	}
+	var recurrence = todo.Recurrence.Anchored(*todo.DueAt)
+	nextDue, ok := recurrence.Next(*todo.DueAt, todo.Occurrence)
+	if !ok {
+		return time.Time{}, 0, nil, false
+	}
+	var seriesId = todo.Id
+	if todo.SeriesId != nil {
+		seriesId = *todo.SeriesId
+	}
+	return nextDue, seriesId, recurrence, true
+}
+
+// shiftedReminder keeps the distance between reminder and due date of the todo for the next due date.
+func shiftedReminder(todo model.Todo, nextDue time.Time) *time.Time {
+	if todo.RemindAt == nil || todo.DueAt == nil {
+		return nil
+	}
+	var remindAt = nextDue.Add(todo.RemindAt.Sub(*todo.DueAt))
+	return &remindAt
+}
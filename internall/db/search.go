@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"html"
+	"strings"
+)
+
+// snippetStart and snippetStop enclose the matches of a snippet until it is escaped,
+// they are private use characters so the text of a todo does not clash with them.
+const (
+	snippetStart = "\ue000"
+	snippetStop  = "\ue001"
+)
+
+const headlineOptions = "StartSel=" + snippetStart + ", StopSel=" + snippetStop + ", MaxFragments=2, HighlightAll=false"
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// markSnippet escapes the snippet as HTML and only then turns the enclosed matches
+// into mark elements, so markup in a todo is never served as markup.
+func markSnippet(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+func (p *PostgresStore) SearchTodos(ctx context.Context, userId int, text string, limit int) ([]model.TodoSearchResult, error) {
+	var results = make([]model.TodoSearchResult, 0)
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+", "+
+		"ts_rank(item.search_vector, query) AS rank, "+
+		"ts_headline('english', item.title, query, '"+headlineOptions+"'), "+
+		"ts_headline('english', item.description, query, '"+headlineOptions+"') "+
+		"FROM item INNER JOIN account_item ON account_item.item_id = item.id, "+
+		"websearch_to_tsquery('english', $2) AS query "+
+		"WHERE account_item.account_id = $1 AND item.deleted_at IS NULL AND item.search_vector @@ query "+
+		"ORDER BY rank DESC, item.id LIMIT $3", userId, text, limit,
+	)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var result = model.TodoSearchResult{}
+		err = scanTodo(rows, &result.Todo, &result.Rank, &result.TitleSnippet, &result.DescriptionSnippet)
+		if err != nil {
+			return results, err
+		}
+		result.TitleSnippet = markSnippet(result.TitleSnippet)
+		result.DescriptionSnippet = markSnippet(result.DescriptionSnippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("record not found")
+	ErrAccessDenied      = errors.New("access denied")
+	ErrLastOwner         = errors.New("todo must keep at least one owner")
+	ErrCycle             = errors.New("todo cannot become a subtask of itself or of its subtasks")
+	ErrInvalidOrder      = errors.New("order must list every subtask exactly once")
+	ErrTagExists         = errors.New("tag with this name already exists")
+	ErrAlreadyUndone     = errors.New("change is already undone")
+	ErrVersionMismatch   = errors.New("todo was changed by someone else")
+	ErrUnknownState      = errors.New("status is not a state of the workflow")
+	ErrInvalidTransition = errors.New("workflow does not allow this transition")
+	ErrQuotaExceeded     = errors.New("attachment quota exceeded")
+)
+
+// BulkError tells which operation of a bulk request failed, none of them was applied.
+// Kind ("Todo", "List" or "Tag") and Id name what the operation failed on.
+type BulkError struct {
+	Index int
+	Kind  string
+	Id    int
+	Err   error
+}
+
+func (b *BulkError) Error() string {
+	return fmt.Sprintf("bulk operation %d: %v", b.Index, b.Err)
+}
+
+func (b *BulkError) Unwrap() error {
+	return b.Err
+}
+
+// BulkChange is what one operation of a bulk request did. Before is the todo as the
+// operation found it, nil for create, and Next the occurrence generated by closing a
+// recurring todo.
+type BulkChange struct {
+	Before *model.Todo
+	Todo   model.Todo
+	Next   *model.Todo
+}
+
+type AccountStore interface {
+	CreateAccount(ctx context.Context, registrationForm request.RegistrationForm) (*model.AccountModel, error)
+	Authentication(ctx context.Context, authenticationForm request.AuthenticationForm) (*model.AccountModel, error)
+	GetUserById(ctx context.Context, id int) (*model.AccountModel, error)
+	GetUserByUserName(ctx context.Context, userName string) (*model.AccountModel, error)
+	GetAccountBy(ctx context.Context, userId int) (*model.AccountModel, error)
+	GetAccounts(ctx context.Context) ([]model.AccountModel, error)
+}
+
+type TodoStore interface {
+	GetTodosBy(ctx context.Context, userId int) ([]model.Todo, error)
+	GetTodosPage(ctx context.Context, userId int, todoQuery request.TodoQuery) (*model.TodoPage, error)
+	SearchTodos(ctx context.Context, userId int, text string, limit int) ([]model.TodoSearchResult, error)
+	GetOpenTodosDue(ctx context.Context, userId int, from *time.Time, to time.Time) ([]model.Todo, error)
+	CreteTodoFor(ctx context.Context, userId int, todoForm request.TodoForm) (*model.Todo, error)
+	// ToggleTodoFor, SetTodoClosed and TransitionTodo also return the next occurrence
+	// they generated when they closed a recurring todo, nil otherwise.
+	// Mutations taking a version only apply while the todo is still at it and return
+	// ErrVersionMismatch otherwise, a nil version skips the check.
+	ToggleTodoFor(ctx context.Context, todoId int, userId int, version *int) (*model.Todo, *model.Todo, error)
+	SetTodoClosed(ctx context.Context, todoId int, userId int, closed bool, version *int) (*model.Todo, *model.Todo, bool, error)
+	TransitionTodo(ctx context.Context, todoId int, userId int, status string, version *int) (*model.Todo, *model.Todo, bool, error)
+	CreateSubtaskFor(ctx context.Context, parentId int, todoForm request.TodoForm) (*model.Todo, error)
+	GetSubtasks(ctx context.Context, parentId int) ([]model.Todo, error)
+	ReorderSubtasks(ctx context.Context, parentId int, subtaskIds []int) error
+	MoveTodo(ctx context.Context, userId int, todoId int, anchorId int, after bool) (int64, error)
+	SetTodoParent(ctx context.Context, todoId int, parentId *int, version *int) error
+	UpdateTodoBy(ctx context.Context, todoId int, todoForm request.TodoForm, version *int) (*model.Todo, error)
+	RemoveTodoBy(ctx context.Context, todoId int, version *int) error
+	// ApplyBulk checks the access of the account and applies the operations in order, it
+	// returns what each of them changed.
+	ApplyBulk(ctx context.Context, userId int, operations []request.BulkOperation) ([]BulkChange, error)
+	GetTrashedTodos(ctx context.Context, userId int) ([]model.Todo, error)
+	GetTrashedTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error)
+	RestoreTodoBy(ctx context.Context, todoId int) error
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	GetTodoBy(ctx context.Context, todoId int) (*model.Todo, error)
+	GetTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error)
+	ShareTodo(ctx context.Context, todoId int, accountId int, role model.Role) error
+	GetCollaborators(ctx context.Context, todoId int) ([]model.Collaborator, error)
+	RevokeTodoAccess(ctx context.Context, todoId int, accountId int) error
+	AssignTodo(ctx context.Context, todoId int, assigneeId *int, version *int) (*model.Todo, error)
+}
+
+type ListStore interface {
+	GetListsBy(ctx context.Context, userId int, includeArchived bool) ([]model.List, error)
+	CreateListFor(ctx context.Context, userId int, listForm request.ListForm) (*model.List, error)
+	GetListBy(ctx context.Context, listId int) (*model.List, error)
+	UpdateListBy(ctx context.Context, listId int, listForm request.ListForm) (*model.List, error)
+	RemoveListBy(ctx context.Context, listId int) error
+	GetTodosByList(ctx context.Context, userId int, listId int) ([]model.Todo, error)
+	GetWorkflow(ctx context.Context, listId *int) (*model.Workflow, error)
+	SetWorkflow(ctx context.Context, listId int, workflow model.Workflow) error
+}
+
+type TagStore interface {
+	GetTagsBy(ctx context.Context, userId int) ([]model.Tag, error)
+	CreateTagFor(ctx context.Context, userId int, tagForm request.TagForm) (*model.Tag, error)
+	GetTagBy(ctx context.Context, tagId int) (*model.Tag, error)
+	UpdateTagBy(ctx context.Context, tagId int, tagForm request.TagForm) (*model.Tag, error)
+	RemoveTagBy(ctx context.Context, tagId int) error
+	GetTodoTags(ctx context.Context, userId int, todoId int) ([]model.Tag, error)
+	AttachTag(ctx context.Context, todoId int, tagId int) error
+	DetachTag(ctx context.Context, todoId int, tagId int) error
+}
+
+type EventStore interface {
+	AddTodoEvent(ctx context.Context, todoId int, actorId int, action model.TodoAction, before interface{}, after interface{}) error
+	GetTodoHistory(ctx context.Context, todoId int, beforeId int, limit int) ([]model.TodoEvent, error)
+	GetActivityFeed(ctx context.Context, userId int, beforeId int, limit int) ([]model.TodoEvent, error)
+	GetLastTodoEvent(ctx context.Context, todoId int, actions []model.TodoAction, window time.Duration) (*model.TodoEvent, bool, error)
+	MarkTodoEventUndone(ctx context.Context, eventId int) error
+}
+
+type AttachmentStore interface {
+	// CreateAttachment saves the metadata unless the account would exceed its quota in bytes.
+	CreateAttachment(ctx context.Context, attachment *model.Attachment, quota int64) error
+	GetAttachments(ctx context.Context, todoId int) ([]model.Attachment, error)
+	GetAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error)
+	RemoveAttachmentBy(ctx context.Context, todoId int, attachmentId int) (*model.Attachment, error)
+	GetAttachmentUsage(ctx context.Context, accountId int) (int64, error)
+	// PurgeDetachedAttachments removes attachments of purged todos and returns their storage keys.
+	PurgeDetachedAttachments(ctx context.Context) ([]string, error)
+}
+
+type CommentStore interface {
+	CreateCommentFor(ctx context.Context, todoId int, userId int, commentForm request.CommentForm) (*model.Comment, error)
+	GetComments(ctx context.Context, todoId int, afterId int, limit int) ([]model.Comment, error)
+	GetCommentBy(ctx context.Context, todoId int, commentId int) (*model.Comment, error)
+	UpdateCommentBy(ctx context.Context, commentId int, commentForm request.CommentForm) (*model.Comment, error)
+	RemoveCommentBy(ctx context.Context, commentId int) error
+}
+
+type NotificationStore interface {
+	AddNotification(ctx context.Context, accountId int, kind model.NotificationKind, todoId int, actorId int, data interface{}) error
+	GetNotifications(ctx context.Context, accountId int, beforeId int, limit int, unreadOnly bool) ([]model.Notification, error)
+	CountUnreadNotifications(ctx context.Context, accountId int) (int, error)
+	MarkNotificationRead(ctx context.Context, accountId int, notificationId int) (*model.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context, accountId int) (int64, error)
+	// SendDueReminders notifies every account linked to an open todo whose reminder time has come,
+	// each reminder time is notified once.
+	SendDueReminders(ctx context.Context, now time.Time) (int64, error)
+}
+
+type Store interface {
+	AccountStore
+	TodoStore
+	ListStore
+	TagStore
+	EventStore
+	AttachmentStore
+	CommentStore
+	NotificationStore
+	Close() error
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/jackc/pgx/v4"
+	"sort"
+)
+
+// CreateSubtaskFor creates a todo under the parent, it is shared with every collaborator of the parent.
+func (p *PostgresStore) CreateSubtaskFor(ctx context.Context, parentId int, todoForm request.TodoForm) (*model.Todo, error) {
+	var todo = &model.Todo{}
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return todo, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	var todoId int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO item(title, description, closed, list_id, due_at, priority, remind_at, recurrence, "+
+			"parent_id, status, subtask_position) VALUES($1, $2, false, $3, $4, $5, $6, $7, $8, "+initialStateSQL("$3")+", "+
+			"(SELECT coalesce(max(subtask_position), 0) + 1 FROM item WHERE parent_id = $8)) RETURNING id",
+		todoForm.Title, todoForm.Description, todoForm.ListId, utcTime(todoForm.DueAt),
+		todoForm.EffectivePriority(), utcTime(todoForm.RemindAt), todoForm.Recurrence, parentId,
+	).Scan(&todoId)
+	if err != nil {
+		return todo, err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO account_item (account_id, item_id, role, position) "+
+		"SELECT account_id, $2, role, "+nextPositionSQL("account_item.account_id")+" "+
+		"FROM account_item WHERE item_id = $1", parentId, todoId)
+	if err != nil {
+		return todo, err
+	}
+	err = scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM item WHERE id = $1", todoId), todo)
+	return todo, err
+}
+
+func (p *PostgresStore) GetSubtasks(ctx context.Context, parentId int) ([]model.Todo, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+" FROM item "+
+		"WHERE item.parent_id = $1 AND item.deleted_at IS NULL ORDER BY item.subtask_position, item.id", parentId,
+	)
+	if err != nil {
+		return make([]model.Todo, 0), err
+	}
+	return scanTodos(rows)
+}
+
+func (p *PostgresStore) ReorderSubtasks(ctx context.Context, parentId int, subtaskIds []int) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	rows, err := tx.Query(ctx, "SELECT id FROM item WHERE parent_id = $1 AND deleted_at IS NULL FOR UPDATE", parentId)
+	if err != nil {
+		return err
+	}
+	var currentIds = make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		currentIds = append(currentIds, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if !sameIds(currentIds, subtaskIds) {
+		err = ErrInvalidOrder
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE item SET subtask_position = ordered.position "+
+		"FROM unnest($1::int[]) WITH ORDINALITY AS ordered(id, position) WHERE item.id = ordered.id", subtaskIds)
+	return err
+}
+
+// SetTodoParent moves the todo under the parent or makes it a top level todo when parentId is nil.
+// Parent changes are serialized with an advisory lock, so concurrent moves cannot build a cycle.
+func (p *PostgresStore) SetTodoParent(ctx context.Context, todoId int, parentId *int, version *int) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('item.parent_id'))"); err != nil {
+		return err
+	}
+	if parentId != nil {
+		var cycle bool
+		err = tx.QueryRow(ctx, "WITH RECURSIVE ancestor(id, parent_id) AS ("+
+			"SELECT id, parent_id FROM item WHERE id = $1 "+
+			"UNION ALL SELECT item.id, item.parent_id FROM item INNER JOIN ancestor ON item.id = ancestor.parent_id"+
+			") SELECT EXISTS(SELECT 1 FROM ancestor WHERE id = $2)", *parentId, todoId,
+		).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			err = ErrCycle
+			return err
+		}
+	}
+	tag, err := tx.Exec(ctx, "UPDATE item SET parent_id = $1, version = version + 1, subtask_position = "+
+		"(SELECT coalesce(max(subtask_position), 0) + 1 FROM item WHERE parent_id = $1) "+
+		"WHERE id = $2 AND ($3::int IS NULL OR version = $3)", parentId, todoId, version,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = missingTodoError(ctx, tx, todoId, version)
+	}
+	return err
+}
+
+func sameIds(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var sortedA = append([]int(nil), a...)
+	var sortedB = append([]int(nil), b...)
+	sort.Ints(sortedA)
+	sort.Ints(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
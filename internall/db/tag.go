@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"strings"
+)
+
+const tagColumns = "tag.id, tag.account_id, tag.name, tag.created_on"
+
+const uniqueViolation = "23505"
+
+func scanTag(row pgx.Row, tag *model.Tag) error {
+	return row.Scan(
+		&tag.Id,
+		&tag.AccountId,
+		&tag.Name,
+		&tag.CreatedOn,
+	)
+}
+
+func scanTags(rows pgx.Rows) ([]model.Tag, error) {
+	defer rows.Close()
+	var tags = make([]model.Tag, 0)
+	for rows.Next() {
+		var tag = model.Tag{}
+		if err := scanTag(rows, &tag); err != nil {
+			return tags, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func (p *PostgresStore) GetTagsBy(ctx context.Context, userId int) ([]model.Tag, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+tagColumns+" FROM tag WHERE account_id = $1 ORDER BY lower(name)", userId)
+	if err != nil {
+		return make([]model.Tag, 0), err
+	}
+	return scanTags(rows)
+}
+
+func (p *PostgresStore) CreateTagFor(ctx context.Context, userId int, tagForm request.TagForm) (*model.Tag, error) {
+	var tag = &model.Tag{}
+	err := scanTag(p.connectionDB.QueryRow(ctx, "INSERT INTO tag (account_id, name) VALUES ($1, $2) RETURNING "+tagColumns,
+		userId, strings.TrimSpace(tagForm.Name),
+	), tag)
+	if isUniqueViolation(err) {
+		return tag, ErrTagExists
+	}
+	return tag, err
+}
+
+func (p *PostgresStore) GetTagBy(ctx context.Context, tagId int) (*model.Tag, error) {
+	var tag = &model.Tag{}
+	err := scanTag(p.connectionDB.QueryRow(ctx, "SELECT "+tagColumns+" FROM tag WHERE id = $1", tagId), tag)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tag, ErrNotFound
+	}
+	return tag, err
+}
+
+func (p *PostgresStore) UpdateTagBy(ctx context.Context, tagId int, tagForm request.TagForm) (*model.Tag, error) {
+	var tag = &model.Tag{}
+	err := scanTag(p.connectionDB.QueryRow(ctx, "UPDATE tag SET name = $1 WHERE id = $2 RETURNING "+tagColumns,
+		strings.TrimSpace(tagForm.Name), tagId,
+	), tag)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return tag, ErrNotFound
+	case isUniqueViolation(err):
+		return tag, ErrTagExists
+	}
+	return tag, err
+}
+
+func (p *PostgresStore) RemoveTagBy(ctx context.Context, tagId int) error {
+	_, err := p.connectionDB.Exec(ctx, "DELETE FROM tag WHERE id = $1", tagId)
+	return err
+}
+
+// GetTodoTags returns the tags of the account attached to the todo, tags of other collaborators stay private.
+func (p *PostgresStore) GetTodoTags(ctx context.Context, userId int, todoId int) ([]model.Tag, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+tagColumns+" "+
+		"FROM tag INNER JOIN item_tag ON item_tag.tag_id = tag.id "+
+		"WHERE tag.account_id = $1 AND item_tag.item_id = $2 ORDER BY lower(tag.name)", userId, todoId,
+	)
+	if err != nil {
+		return make([]model.Tag, 0), err
+	}
+	return scanTags(rows)
+}
+
+// attachTagQuery links the tag $2 to the todo $1 unless the todo is in the trash. An existing
+// link is written again, so the statement only touches no row when the todo is gone.
+const attachTagQuery = "INSERT INTO item_tag (item_id, tag_id) SELECT $1, $2 " +
+	"WHERE EXISTS (SELECT 1 FROM item WHERE id = $1 AND deleted_at IS NULL) " +
+	"ON CONFLICT (item_id, tag_id) DO UPDATE SET tag_id = excluded.tag_id"
+
+func (p *PostgresStore) AttachTag(ctx context.Context, todoId int, tagId int) error {
+	tag, err := p.connectionDB.Exec(ctx, attachTagQuery, todoId, tagId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStore) DetachTag(ctx context.Context, todoId int, tagId int) error {
+	_, err := p.connectionDB.Exec(ctx, "DELETE FROM item_tag WHERE item_id = $1 AND tag_id = $2", todoId, tagId)
+	return err
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"strings"
+	"time"
+)
+
+var todoSortColumns = map[request.TodoSortField]string{
+	request.SortByPosition:  "account_item.position",
+	request.SortById:        "item.id",
+	request.SortByTitle:     "item.title",
+	request.SortByCreatedOn: "item.created_on",
+	request.SortByUpdatedOn: "item.updated_on",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (p *PostgresStore) GetTodosPage(ctx context.Context, userId int, todoQuery request.TodoQuery) (*model.TodoPage, error) {
+	var page = &model.TodoPage{Todos: make([]model.Todo, 0)}
+	var conditions = []string{"account_item.account_id = $1", "item.deleted_at IS NULL"}
+	var args = []interface{}{userId}
+	var addCondition = func(format string, values ...interface{}) {
+		var placeholders = make([]interface{}, 0, len(values))
+		for _, value := range values {
+			args = append(args, value)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf(format, placeholders...))
+	}
+	if todoQuery.Closed != nil {
+		addCondition("item.closed = %s", *todoQuery.Closed)
+	}
+	if todoQuery.CreatedAfter != nil {
+		addCondition("item.created_on >= %s", *todoQuery.CreatedAfter)
+	}
+	if todoQuery.CreatedBefore != nil {
+		addCondition("item.created_on < %s", *todoQuery.CreatedBefore)
+	}
+	if todoQuery.UpdatedAfter != nil {
+		addCondition("item.updated_on >= %s", *todoQuery.UpdatedAfter)
+	}
+	if todoQuery.UpdatedBefore != nil {
+		addCondition("item.updated_on < %s", *todoQuery.UpdatedBefore)
+	}
+	if len(todoQuery.Text) != 0 {
+		var pattern = "%" + likeEscaper.Replace(todoQuery.Text) + "%"
+		addCondition("(item.title ILIKE %[1]s OR item.description ILIKE %[1]s)", pattern)
+	}
+	for _, alternatives := range todoQuery.Tags {
+		addCondition("EXISTS (SELECT 1 FROM item_tag INNER JOIN tag ON tag.id = item_tag.tag_id "+
+			"WHERE item_tag.item_id = item.id AND tag.account_id = $1 AND lower(tag.name) = ANY(%s))", alternatives)
+	}
+	var sortColumn = todoSortColumns[todoQuery.SortField]
+	var direction, comparison = "ASC", ">"
+	if todoQuery.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor := todoQuery.Cursor; cursor != nil {
+		var value interface{} = cursor.Value
+		switch cursor.SortField {
+		case request.SortById:
+			value = cursor.Id
+		case request.SortByCreatedOn, request.SortByUpdatedOn:
+			value, _ = cursor.TimeValue()
+		case request.SortByPosition:
+			value, _ = cursor.PositionValue()
+		}
+		addCondition("("+sortColumn+", item.id) "+comparison+" (%s, %s)", value, cursor.Id)
+	}
+	args = append(args, todoQuery.Limit+1)
+	var query = fmt.Sprintf("SELECT %s, account_item.position FROM item INNER JOIN account_item ON account_item.item_id = item.id "+
+		"WHERE %s ORDER BY %s %s, item.id %s LIMIT $%d",
+		todoColumns, strings.Join(conditions, " AND "), sortColumn, direction, direction, len(args),
+	)
+	rows, err := p.connectionDB.Query(ctx, query, args...)
+	if err != nil {
+		return page, err
+	}
+	if page.Todos, err = scanPositionedTodos(rows); err != nil {
+		return page, err
+	}
+	paginate(page, todoQuery)
+	return page, nil
+}
+
+// paginate trims the extra todo fetched to detect a following page and fills the next cursor.
+func paginate(page *model.TodoPage, todoQuery request.TodoQuery) {
+	if len(page.Todos) <= todoQuery.Limit {
+		return
+	}
+	page.Todos = page.Todos[:todoQuery.Limit]
+	page.NextCursor = todoQuery.NextCursor(page.Todos[len(page.Todos)-1])
+}
+
+// GetOpenTodosDue returns not closed todos due in [from, to), a nil from means any time before to.
+func (p *PostgresStore) GetOpenTodosDue(ctx context.Context, userId int, from *time.Time, to time.Time) ([]model.Todo, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+" "+
+		"FROM item INNER JOIN account_item ON account_item.item_id = item.id "+
+		"WHERE account_item.account_id = $1 AND item.deleted_at IS NULL AND NOT item.closed AND item.due_at < $3 "+
+		"AND ($2::timestamp IS NULL OR item.due_at >= $2) ORDER BY item.due_at, item.id",
+		userId, utcTime(from), to.UTC(),
+	)
+	if err != nil {
+		return make([]model.Todo, 0), err
+	}
+	return scanTodos(rows)
+}
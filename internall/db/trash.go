@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+// GetTrashedTodos returns todos in the trash which the account owns, most recently removed first.
+func (p *PostgresStore) GetTrashedTodos(ctx context.Context, userId int) ([]model.Todo, error) {
+	rows, err := p.connectionDB.Query(ctx, "SELECT "+todoColumns+" "+
+		"FROM item INNER JOIN account_item ON account_item.item_id = item.id "+
+		"WHERE account_item.account_id = $1 AND account_item.role = $2 AND item.deleted_at IS NOT NULL "+
+		"ORDER BY item.deleted_at DESC, item.id", userId, model.RoleOwner,
+	)
+	if err != nil {
+		return make([]model.Todo, 0), err
+	}
+	return scanTodos(rows)
+}
+
+func (p *PostgresStore) GetTrashedTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error) {
+	return p.todoRole(ctx, userId, todoId, true)
+}
+
+// RestoreTodoBy takes the todo and the subtasks removed together with it out of
+// the trash. A todo whose parent is still in the trash becomes a top-level todo.
+func (p *PostgresStore) RestoreTodoBy(ctx context.Context, todoId int) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	tag, err := tx.Exec(ctx, "WITH RECURSIVE subtree AS ("+
+		"SELECT id, deleted_at FROM item WHERE id = $1 AND deleted_at IS NOT NULL "+
+		"UNION ALL SELECT item.id, item.deleted_at FROM item INNER JOIN subtree ON item.parent_id = subtree.id "+
+		"WHERE item.deleted_at = subtree.deleted_at"+
+		") UPDATE item SET deleted_at = NULL, version = version + 1 FROM subtree WHERE item.id = subtree.id", todoId,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = ErrNotFound
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE item SET parent_id = NULL WHERE id = $1 AND "+
+		"parent_id IN (SELECT id FROM item WHERE deleted_at IS NOT NULL)", todoId,
+	)
+	return err
+}
+
+// PurgeTrash permanently removes todos which were moved into the trash before the given time.
+func (p *PostgresStore) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.connectionDB.Exec(ctx, "DELETE FROM item WHERE deleted_at < $1", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/jackc/pgx/v4"
+	"strings"
+)
+
+var defaultWorkflow = model.DefaultWorkflow()
+
+// defaultStatesSQL lists the state names of the default workflow as SQL literals.
+var defaultStatesSQL = func() string {
+	var names = make([]string, 0, len(defaultWorkflow.States))
+	for _, state := range defaultWorkflow.States {
+		names = append(names, "'"+state.Name+"'")
+	}
+	return strings.Join(names, ", ")
+}()
+
+// initialStateSQL selects the initial state of the workflow of the list in listExpr.
+func initialStateSQL(listExpr string) string {
+	return fmt.Sprintf("coalesce((SELECT name FROM list_state WHERE list_id = %s ORDER BY position LIMIT 1), '%s')",
+		listExpr, defaultWorkflow.Initial())
+}
+
+// terminalStateSQL selects the first terminal state of the workflow of the list in listExpr.
+func terminalStateSQL(listExpr string) string {
+	return fmt.Sprintf("coalesce((SELECT name FROM list_state WHERE list_id = %s AND terminal "+
+		"ORDER BY position LIMIT 1), '%s')", listExpr, defaultWorkflow.FirstTerminal())
+}
+
+// fitStatusSQL is model.Workflow.Fit for the workflow of the list in listExpr.
+func fitStatusSQL(listExpr string, statusExpr string, closedExpr string) string {
+	return fmt.Sprintf("CASE WHEN %[2]s IN (SELECT name FROM list_state WHERE list_id = %[1]s) "+
+		"OR (NOT EXISTS (SELECT 1 FROM list_state WHERE list_id = %[1]s) AND %[2]s IN (%[4]s)) THEN %[2]s "+
+		"WHEN %[3]s THEN %[5]s ELSE %[6]s END",
+		listExpr, statusExpr, closedExpr, defaultStatesSQL, terminalStateSQL(listExpr), initialStateSQL(listExpr))
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// GetWorkflow returns the workflow of the list, the default one when the list has none or listId is nil.
+func (p *PostgresStore) GetWorkflow(ctx context.Context, listId *int) (*model.Workflow, error) {
+	return getWorkflow(ctx, p.connectionDB, listId)
+}
+
+func getWorkflow(ctx context.Context, q querier, listId *int) (*model.Workflow, error) {
+	var workflow = model.DefaultWorkflow()
+	if listId == nil {
+		return &workflow, nil
+	}
+	rows, err := q.Query(ctx, "SELECT name, terminal FROM list_state WHERE list_id = $1 ORDER BY position", *listId)
+	if err != nil {
+		return nil, err
+	}
+	var states = make([]model.WorkflowState, 0)
+	for rows.Next() {
+		var state model.WorkflowState
+		if err = rows.Scan(&state.Name, &state.Terminal); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil || len(states) == 0 {
+		return &workflow, err
+	}
+	rows, err = q.Query(ctx, "SELECT from_state, to_state FROM list_transition WHERE list_id = $1 "+
+		"ORDER BY from_state, to_state", *listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var transitions = make([]model.WorkflowTransition, 0)
+	for rows.Next() {
+		var transition model.WorkflowTransition
+		if err = rows.Scan(&transition.From, &transition.To); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+	workflow = model.Workflow{States: states, Transitions: transitions}
+	return &workflow, rows.Err()
+}
+
+// SetWorkflow replaces the workflow of the list. Todos of the list in a state which
+// is gone are moved by model.Workflow.Fit, then closed is derived from their state again.
+func (p *PostgresStore) SetWorkflow(ctx context.Context, listId int, workflow model.Workflow) error {
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	if _, err = tx.Exec(ctx, "DELETE FROM list_state WHERE list_id = $1", listId); err != nil {
+		return err
+	}
+	for position, state := range workflow.States {
+		_, err = tx.Exec(ctx, "INSERT INTO list_state (list_id, name, position, terminal) VALUES ($1, $2, $3, $4)",
+			listId, state.Name, position, state.Terminal)
+		if err != nil {
+			return err
+		}
+	}
+	for _, transition := range workflow.Transitions {
+		_, err = tx.Exec(ctx, "INSERT INTO list_transition (list_id, from_state, to_state) VALUES ($1, $2, $3)",
+			listId, transition.From, transition.To)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, "UPDATE item SET status = "+fitStatusSQL("$1", "status", "closed")+", version = version + 1 "+
+		"WHERE list_id = $1 AND status NOT IN (SELECT name FROM list_state WHERE list_id = $1)", listId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "WITH derived AS (SELECT item.id, list_state.terminal FROM item "+
+		"INNER JOIN list_state ON list_state.list_id = item.list_id AND list_state.name = item.status "+
+		"WHERE item.list_id = $1 AND item.closed <> list_state.terminal) "+
+		"UPDATE item SET closed = derived.terminal, "+
+		"closed_at = CASE WHEN derived.terminal THEN current_timestamp END, closed_by = NULL, version = version + 1 "+
+		"FROM derived WHERE item.id = derived.id", listId)
+	return err
+}
+
+// TransitionTodo moves the todo into the state of its workflow and reports whether
+// its status changed. Closed follows the terminal flag of the new state, closing a
+// recurring todo generates its next occurrence in the same transaction.
+func (p *PostgresStore) TransitionTodo(ctx context.Context, todoId int, userId int, status string, version *int) (*model.Todo, *model.Todo, bool, error) {
+	var todo = &model.Todo{}
+	tx, err := p.connectionDB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return todo, nil, false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			tx.Commit(ctx)
+		}
+	}()
+	err = scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM item "+
+		"WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2) FOR UPDATE", todoId, version), todo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = missingTodoError(ctx, tx, todoId, version)
+	}
+	if err != nil {
+		return todo, nil, false, err
+	}
+	workflow, err := getWorkflow(ctx, tx, todo.ListId)
+	if err != nil {
+		return todo, nil, false, err
+	}
+	state, ok := workflow.State(status)
+	if !ok {
+		err = ErrUnknownState
+		return todo, nil, false, err
+	}
+	if todo.Status == status {
+		return todo, nil, false, nil
+	}
+	if !workflow.Allows(todo.Status, status) {
+		err = ErrInvalidTransition
+		return todo, nil, false, err
+	}
+	var wasClosed = todo.Closed
+	err = scanTodo(tx.QueryRow(ctx, "UPDATE item SET status = $2, closed = $3, "+
+		"closed_at = CASE WHEN NOT $3 THEN NULL WHEN closed THEN closed_at ELSE current_timestamp END, "+
+		"closed_by = CASE WHEN NOT $3 THEN NULL WHEN closed THEN closed_by ELSE $4 END, "+
+		"version = version + 1 WHERE id = $1 RETURNING "+todoColumns, todoId, status, state.Terminal, userId,
+	), todo)
+	if err != nil {
+		return todo, nil, false, err
+	}
+	var next *model.Todo
+	if todo.Closed && !wasClosed {
+		next, err = insertNextOccurrence(ctx, tx, *todo)
+	}
+	return todo, next, err == nil, err
+}
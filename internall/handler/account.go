@@ -12,6 +12,10 @@ import (
 	"strconv"
 )
 
+type AccountHandler struct {
+	Accounts db.AccountStore
+}
+
 // UserInfoHandler docs
 // @Summary Get account info
 // @Description get account info by id
@@ -26,7 +30,7 @@ import (
 // @Failure  500 {object} model.ResponseError
 // @Failure  400 {object} model.ResponseError
 // @Router   /account/user/{id} [get]
-func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AccountHandler) UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	accountId, err := strconv.Atoi(vars["id"])
@@ -38,7 +42,7 @@ func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(errorModel.Code)
 		logger.Error("Cannot parse user id from request", zap.Error(err))
 	}
-	accountModel, err := db.GetAccountBy(accountId)
+	accountModel, err := h.Accounts.GetAccountBy(r.Context(), accountId)
 	if err != nil {
 		var errorModel = model.ResponseError{
 			Code:    http.StatusInternalServerError,
@@ -73,10 +77,10 @@ func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure  400 {object} model.ResponseError
 // @Failure  401 {object} model.ResponseError
 // @Router   /account/users/ [get]
-func UsersInfoHanlder(w http.ResponseWriter, r *http.Request) {
+func (h *AccountHandler) UsersInfoHanlder(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 
-	accounts, err := db.GetAccounts()
+	accounts, err := h.Accounts.GetAccounts(r.Context())
 	if err != nil {
 		var errorModel = model.ResponseError{
 			Code:    http.StatusInternalServerError,
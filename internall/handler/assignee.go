@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AssignTodoHandler docs
+// @Summary Assign todo to a collaborator
+// @Description the assignee must be able to edit the todo, a null user-name unassigns it. The new assignee is notified
+// @Tags todo
+// @ID assign-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.AssignForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/assignee [put]
+func (h *TodoHandler) AssignTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	var assignForm request.AssignForm
+	if err := json.NewDecoder(r.Body).Decode(&assignForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve assign form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var assigneeId *int
+	if assignForm.UserName != nil {
+		var ok bool
+		if assigneeId, ok = h.findAssignee(w, r, todoId, *assignForm.UserName); !ok {
+			return
+		}
+	}
+	before, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, before) {
+		return
+	}
+	todo, err := h.Todos.AssignTodo(r.Context(), todoId, assigneeId, expectedVersion(r, before))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, before)
+		return
+	}
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation assign todo",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Todo %d not found", todoId),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, todoId, model.TodoAssigned, before, todo)
+	if assigneeId != nil && *assigneeId != userId && !sameAssignee(before.AssigneeId, assigneeId) {
+		h.notifyAssignee(r, userId, todo)
+	}
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// findAssignee looks up the account by user name and makes sure it can edit the todo.
+// On failure the response is written and false is returned.
+func (h *TodoHandler) findAssignee(w http.ResponseWriter, r *http.Request, todoId int, userName string) (*int, bool) {
+	account, err := h.Accounts.GetUserByUserName(r.Context(), userName)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve account from db",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Account %s not found", userName),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return nil, false
+	}
+	role, err := h.Todos.GetTodoRole(r.Context(), account.Id, todoId)
+	if err == nil && !role.Allows(model.RoleEditor) {
+		err = db.ErrAccessDenied
+	}
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot check access of assignee to todo",
+		}
+		if errors.Is(err, db.ErrAccessDenied) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("Account %s cannot edit todo %d, share it as editor first", userName, todoId),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return nil, false
+	}
+	return &account.Id, true
+}
+
+// notifyAssignee tells the new assignee that the todo was assigned to it.
+func (h *TodoHandler) notifyAssignee(r *http.Request, userId int, todo *model.Todo) {
+	if err := h.Notifications.AddNotification(r.Context(), *todo.AssigneeId, model.NotificationAssigned, todo.Id, userId, todo); err != nil {
+		logger.Error("Cannot notify account about assignment",
+			zap.Int("todo id", todo.Id),
+			zap.Int("account id", *todo.AssigneeId),
+			zap.Error(err),
+		)
+	}
+}
+
+func sameAssignee(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/http"
+	"testing"
+)
+
+// notifications returns the notifications of the account of the kind.
+func (s *testServer) notifications(t *testing.T, accountId int, kind model.NotificationKind) []model.Notification {
+	t.Helper()
+	notifications, err := s.store.GetNotifications(context.Background(), accountId, 0, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ofKind = make([]model.Notification, 0)
+	for _, notification := range notifications {
+		if notification.Kind == kind {
+			ofKind = append(ofKind, notification)
+		}
+	}
+	return ofKind
+}
+
+func TestAssigningTodoNotifiesAssignee(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Fix the roof"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path+"/share", `{"user-name":"bob","role":"editor"}`), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"bob"}`), http.StatusOK)
+	if assignee := s.getTodo(t, alice, todo.Id).AssigneeId; assignee == nil || *assignee != bob {
+		t.Fatalf("expected todo assigned to %d, got %v", bob, assignee)
+	}
+	var assigned = s.notifications(t, bob, model.NotificationAssigned)
+	if len(assigned) != 1 || assigned[0].TodoId == nil || *assigned[0].TodoId != todo.Id {
+		t.Fatalf("expected one assigned notification about todo %d, got %+v", todo.Id, assigned)
+	}
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"alice"}`), http.StatusOK)
+	if len(s.notifications(t, alice, model.NotificationAssigned)) != 0 {
+		t.Fatal("expected no notification when assigning oneself")
+	}
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":null}`), http.StatusOK)
+	if assignee := s.getTodo(t, alice, todo.Id).AssigneeId; assignee != nil {
+		t.Fatalf("expected todo to be unassigned, got %d", *assignee)
+	}
+}
+
+func TestAssigneeMustBeAbleToEditTodo(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	s.signUp(t, "carol")
+	var todo = s.addTodo(t, alice, `{"title":"Fix the roof"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path+"/share", `{"user-name":"bob","role":"viewer"}`), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"bob"}`), http.StatusConflict)
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"carol"}`), http.StatusConflict)
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"dave"}`), http.StatusNotFound)
+	expectStatus(t, s.do(t, bob, http.MethodPut, path+"/assignee", `{"user-name":"alice"}`), http.StatusForbidden)
+	if len(s.notifications(t, bob, model.NotificationAssigned)) != 0 {
+		t.Fatal("expected no assigned notification for a rejected assignment")
+	}
+}
+
+func TestRevokingAccessUnassignsTodo(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Fix the roof"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path+"/share", `{"user-name":"bob","role":"editor"}`), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPut, path+"/assignee", `{"user-name":"bob"}`), http.StatusOK)
+	if err := s.store.RevokeTodoAccess(context.Background(), todo.Id, bob); err != nil {
+		t.Fatal(err)
+	}
+	if assignee := s.getTodo(t, alice, todo.Id).AssigneeId; assignee != nil {
+		t.Fatalf("expected revoked assignee to be cleared, got %d", *assignee)
+	}
+}
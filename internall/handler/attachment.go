@@ -0,0 +1,466 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/storage"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// attachmentFormField is the multipart field carrying the uploaded file.
+const attachmentFormField = "file"
+
+// multipartOverhead is how many bytes of the request body beyond the file are
+// accepted for boundaries and part headers.
+const multipartOverhead = 1 << 20
+
+// maxFileNameLength matches the file_name column of the attachment table.
+const maxFileNameLength = 255
+
+// sniffLength is how many leading bytes http.DetectContentType looks at.
+const sniffLength = 512
+
+// allowedAttachmentTypes are the sniffed media types which may be uploaded,
+// a type ending with a slash allows the whole family.
+var allowedAttachmentTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"application/pdf",
+	"application/zip",
+	"application/x-gzip",
+	"text/plain",
+}
+
+// AttachmentsHandler docs
+// @Summary Get attachments of todo
+// @Tags todo
+// @ID attachments-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Success  200 {array} model.Attachment
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/attachments [get]
+func (h *TodoHandler) AttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	attachments, err := h.Attachments.GetAttachments(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve attachments",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(attachments); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// UploadAttachmentHandler docs
+// @Summary Upload attachment to todo
+// @Description the content type is sniffed from the file, the one sent by the client is ignored. The size of the file counts towards the quota of the uploader
+// @Tags todo
+// @ID upload-attachment-handler
+// @Accept   mpfd
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    file      formData   file     true  "file"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Attachment
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  413 {object} model.ResponseError
+// @Failure  415 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/attachments [post]
+func (h *TodoHandler) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok || !h.checkTodoPrecondition(w, r, todoId) {
+		return
+	}
+	usage, err := h.Attachments.GetAttachmentUsage(r.Context(), userId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve attachment usage",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if usage >= h.AttachmentQuota {
+		writeQuotaExceeded(w, userId, h.AttachmentQuota)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxAttachmentSize+multipartOverhead)
+	part, ok := attachmentPart(w, r)
+	if !ok {
+		return
+	}
+	defer part.Close()
+	var fileName = attachmentFileName(part.FileName())
+	if len(fileName) == 0 {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Uploaded file must have a name",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var content = bufio.NewReaderSize(part, sniffLength)
+	head, err := content.Peek(sniffLength)
+	if err != nil && err != io.EOF {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot read uploaded file",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var contentType = http.DetectContentType(head)
+	if !allowedAttachmentType(contentType) {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("Files of type %s cannot be attached", contentType),
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	key, err := storage.NewKey()
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot generate attachment key",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var body = &readRecorder{reader: io.LimitReader(content, h.MaxAttachmentSize+1)}
+	size, err := h.Blobs.Put(r.Context(), key, body)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot store uploaded file",
+		}
+		if body.err != nil {
+			errResponse = model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: "Cannot read uploaded file",
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if size > h.MaxAttachmentSize {
+		h.deleteBlob(r, key)
+		errResponse := model.ResponseError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("File is larger than %d bytes", h.MaxAttachmentSize),
+		}
+		logger.Error(errResponse.Message, zap.Int("user id", userId))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var attachment = &model.Attachment{
+		TodoId:      todoId,
+		AccountId:   userId,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := h.Attachments.CreateAttachment(r.Context(), attachment, h.AttachmentQuota); err != nil {
+		h.deleteBlob(r, key)
+		if errors.Is(err, db.ErrQuotaExceeded) {
+			writeQuotaExceeded(w, userId, h.AttachmentQuota)
+			return
+		}
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation upload attachment",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, todoId, model.TodoAttached, nil, attachment)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(attachment); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// DownloadAttachmentHandler docs
+// @Summary Download attachment of todo
+// @Description the file is always served as a download with its sniffed content type
+// @Tags todo
+// @ID download-attachment-handler
+// @Produce  octet-stream
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    attachmentId      path   int     true  "attachment id"
+// @Success  200 {file} file
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/attachments/{attachmentId} [get]
+func (h *TodoHandler) DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	attachment, ok := h.findAttachment(w, r, todoId)
+	if !ok {
+		return
+	}
+	content, err := h.Blobs.Open(r.Context(), attachment.StorageKey)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot open attachment",
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Content of attachment %d not found", attachment.Id),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", attachment.CreatedOn, seeker)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Error("Error occurred during sending attachment", zap.Error(err))
+	}
+}
+
+// RemoveAttachmentHandler docs
+// @Summary Remove attachment from todo
+// @Tags todo
+// @ID remove-attachment-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    attachmentId      path   int     true  "attachment id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/attachments/{attachmentId} [delete]
+func (h *TodoHandler) RemoveAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok || !h.checkTodoPrecondition(w, r, todoId) {
+		return
+	}
+	attachmentId, err := strconv.Atoi(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve attachment id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	attachment, err := h.Attachments.RemoveAttachmentBy(r.Context(), todoId, attachmentId)
+	if err != nil {
+		writeAttachmentError(w, err, attachmentId, "Cannot complete operation remove attachment")
+		return
+	}
+	h.deleteBlob(r, attachment.StorageKey)
+	h.recordTodoEvent(r, todoId, model.TodoDetached, attachment, nil)
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Removed attachment by %d", attachment.Id),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// findAttachment resolves the attachment id from the path within the todo.
+// On failure the response is written and false is returned.
+func (h *TodoHandler) findAttachment(w http.ResponseWriter, r *http.Request, todoId int) (*model.Attachment, bool) {
+	attachmentId, err := strconv.Atoi(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve attachment id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return nil, false
+	}
+	attachment, err := h.Attachments.GetAttachmentBy(r.Context(), todoId, attachmentId)
+	if err != nil {
+		writeAttachmentError(w, err, attachmentId, "Cannot retrieve attachment")
+		return nil, false
+	}
+	return attachment, true
+}
+
+// deleteBlob removes the stored content of an attachment from the blob storage.
+func (h *TodoHandler) deleteBlob(r *http.Request, key string) {
+	if err := h.Blobs.Delete(r.Context(), key); err != nil {
+		logger.Error("Cannot delete attachment content", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// attachmentPart skips to the file field of the multipart body.
+// On failure the response is written and false is returned.
+func attachmentPart(w http.ResponseWriter, r *http.Request) (*multipart.Part, bool) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Request body must be multipart/form-data",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return nil, false
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			errResponse := model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Request body has no %s field", attachmentFormField),
+			}
+			logger.Error(errResponse.Message, zap.Error(err))
+			writeResponseError(w, errResponse)
+			return nil, false
+		}
+		if part.FormName() == attachmentFormField {
+			return part, true
+		}
+		_ = part.Close()
+	}
+}
+
+// attachmentFileName cleans the name sent by the client, so it is safe to store and echo back.
+func attachmentFileName(name string) string {
+	name = strings.TrimSpace(strings.ToValidUTF8(name, ""))
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func allowedAttachmentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowedAttachmentTypes {
+		if mediaType == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeQuotaExceeded(w http.ResponseWriter, userId int, quota int64) {
+	errResponse := model.ResponseError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: fmt.Sprintf("Attachments may not take more than %d bytes", quota),
+	}
+	logger.Error(errResponse.Message, zap.Int("user id", userId))
+	writeResponseError(w, errResponse)
+}
+
+func writeAttachmentError(w http.ResponseWriter, err error, attachmentId int, message string) {
+	errResponse := model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		errResponse = model.ResponseError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("Attachment %d not found", attachmentId),
+		}
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
+
+// readRecorder remembers the error of the upload body, so a broken request is
+// told apart from a failure of the blob storage.
+type readRecorder struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *readRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
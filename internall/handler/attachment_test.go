@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/storage"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// attachmentServer is a test server whose attachments go into a temporary directory,
+// a single file may hold 64 bytes and an account 100 bytes.
+func attachmentServer(t *testing.T) (*testServer, string) {
+	t.Helper()
+	var s = newTestServer(t)
+	var root = t.TempDir()
+	blobs, err := storage.NewFileSystemStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.todos.Attachments = s.store
+	s.todos.Blobs = blobs
+	s.todos.MaxAttachmentSize = 64
+	s.todos.AttachmentQuota = 100
+	return s, root
+}
+
+// upload posts the content as the file of a multipart form.
+func (s *testServer) upload(t *testing.T, accountId int, todoId int, fileName string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	var form = multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var path = fmt.Sprintf("/todo/%d/attachments", todoId)
+	return s.do(t, accountId, http.MethodPost, path, body.String(), "Content-Type", form.FormDataContentType())
+}
+
+// countBlobs returns how many files the blob store keeps below root.
+func countBlobs(t *testing.T, root string) int {
+	t.Helper()
+	var count = 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestUploadIsCheckedBySniffedTypeAndSize(t *testing.T) {
+	var uploads = []struct {
+		name        string
+		fileName    string
+		content     []byte
+		status      int
+		contentType string
+	}{
+		{"text", "notes.txt", []byte("Pack socks and a towel."), http.StatusOK, "text/plain; charset=utf-8"},
+		{"png", "map.png", []byte("\x89PNG\x0d\x0a\x1a\x0a\x00\x00\x00\x0dIHDR"), http.StatusOK, "image/png"},
+		{"pdf named as text", "ticket.txt", []byte("%PDF-1.4 ticket"), http.StatusOK, "application/pdf"},
+		{"html", "notes.txt", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType, ""},
+		{"executable named as image", "photo.png", []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00"), http.StatusUnsupportedMediaType, ""},
+		{"larger than a file may be", "notes.txt", []byte(strings.Repeat("a", 65)), http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, upload := range uploads {
+		t.Run(upload.name, func(t *testing.T) {
+			var s, root = attachmentServer(t)
+			var alice = s.signUp(t, "alice")
+			var todo = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+			var w = s.upload(t, alice, todo.Id, upload.fileName, upload.content)
+			expectStatus(t, w, upload.status)
+			var stored = 0
+			if upload.status == http.StatusOK {
+				var attachment model.Attachment
+				decodeResponse(t, w, &attachment)
+				if attachment.ContentType != upload.contentType || attachment.Size != int64(len(upload.content)) {
+					t.Fatalf("expected %s of %d bytes, got %+v", upload.contentType, len(upload.content), attachment)
+				}
+				stored = 1
+			}
+			if count := countBlobs(t, root); count != stored {
+				t.Fatalf("expected %d stored files, got %d", stored, count)
+			}
+		})
+	}
+}
+
+func TestUploadBeyondQuotaIsTooLarge(t *testing.T) {
+	var s, root = attachmentServer(t)
+	var alice, bob = s.signUp(t, "alice"), s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	var content = []byte(strings.Repeat("a", 40))
+	expectStatus(t, s.upload(t, alice, todo.Id, "first.txt", content), http.StatusOK)
+	expectStatus(t, s.upload(t, alice, todo.Id, "second.txt", content), http.StatusOK)
+	expectStatus(t, s.upload(t, alice, todo.Id, "third.txt", content), http.StatusRequestEntityTooLarge)
+	if count := countBlobs(t, root); count != 2 {
+		t.Fatalf("expected the rejected file to be deleted, got %d stored files", count)
+	}
+	usage, err := s.store.GetAttachmentUsage(context.Background(), alice)
+	if err != nil || usage != 80 {
+		t.Fatalf("expected a usage of 80 bytes, got %d: %v", usage, err)
+	}
+	expectStatus(t, s.upload(t, alice, todo.Id, "fourth.txt", content[:20]), http.StatusOK)
+	expectStatus(t, s.upload(t, alice, todo.Id, "fifth.txt", content[:1]), http.StatusRequestEntityTooLarge)
+	var own = s.addTodo(t, bob, `{"title":"Bake bread"}`)
+	expectStatus(t, s.upload(t, bob, own.Id, "recipe.txt", content), http.StatusOK)
+}
@@ -11,6 +11,10 @@ import (
 	"net/http"
 )
 
+type AuthenticationHandler struct {
+	Accounts db.AccountStore
+}
+
 // SignInHandler docs
 // @Summary Sign in flow
 // @Tags authentication
@@ -23,7 +27,7 @@ import (
 // @Failure  401 {object} model.ResponseError
 // @Failure  400 {object} model.ResponseError
 // @Router   /authentication/sign-in [post]
-func SignInHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthenticationHandler) SignInHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 
 	var authenticationForm *request.AuthenticationForm
@@ -34,7 +38,7 @@ func SignInHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var accountModel *model.AccountModel
-	if accountModel, err = db.Authentication(*authenticationForm); err != nil {
+	if accountModel, err = h.Accounts.Authentication(r.Context(), *authenticationForm); err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		logger.Error("occurred during check authentication", zap.Error(err))
 		return
@@ -73,7 +77,7 @@ func SignInHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure  500 {object} model.ResponseError
 // @Failure  400 {object} model.ResponseError
 // @Router   /authentication/sign-up [post]
-func SignUpHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthenticationHandler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 
 	var registrationForm request.RegistrationForm
@@ -92,7 +96,7 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 		zap.String("email", registrationForm.Email),
 		zap.String("password", registrationForm.Password),
 	)
-	accountModel, err := db.CreateAccount(registrationForm)
+	accountModel, err := h.Accounts.CreateAccount(r.Context(), registrationForm)
 	if err != nil {
 		logger.Error("During create account", zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
@@ -115,7 +119,7 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure  500 {object} model.ResponseError
 // @Failure  400 {object} model.ResponseError
 // @Router   /authentication/refresh-token [post]
-func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthenticationHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 
 	var credentials model.Credentials
@@ -169,7 +173,7 @@ func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		)
 		return
 	}
-	if accountModel, err = db.GetUserById(userId); err != nil {
+	if accountModel, err = h.Accounts.GetUserById(r.Context(), userId); err != nil {
 		var errorModel = model.ResponseError{
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
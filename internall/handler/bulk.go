@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// BulkTodoHandler docs
+// @Summary Apply several todo operations at once
+// @Description operations (create, close, reopen, delete, move, tag) run in order inside a single transaction, either all of them are applied or none. An operation with a version fails with 412 unless its todo is still at that version. The response holds a result per operation, operations skipped because another one failed report 424
+// @Tags todo
+// @ID bulk-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    body      body   request.BulkForm     true  "form"
+// @Success  200 {object} model.BulkResponse
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.BulkResponse
+// @Failure  404 {object} model.BulkResponse
+// @Failure  412 {object} model.BulkResponse
+// @Failure  500 {object} model.BulkResponse
+// @Router   /todo/bulk [post]
+func (h *TodoHandler) BulkTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var bulkForm request.BulkForm
+	if err := json.NewDecoder(r.Body).Decode(&bulkForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve bulk form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var operations = bulkForm.Operations
+	if len(operations) == 0 || len(operations) > request.MaxBulkOperations {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Bulk request must contain between 1 and %d operations", request.MaxBulkOperations),
+		}
+		logger.Error(errResponse.Message, zap.Int("operations", len(operations)))
+		writeResponseError(w, errResponse)
+		return
+	}
+	for index, operation := range operations {
+		if !operation.IsValidated() {
+			errResponse := model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Operation %d is invalid", index),
+			}
+			logger.Error(errResponse.Message, zap.String("op", string(operation.Op)))
+			writeResponseError(w, errResponse)
+			return
+		}
+	}
+	changes, err := h.Todos.ApplyBulk(r.Context(), userId, operations)
+	if err != nil {
+		var bulkErr *db.BulkError
+		var index = 0
+		var errResponse = model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete bulk operation",
+		}
+		if errors.As(err, &bulkErr) {
+			index = bulkErr.Index
+			errResponse = bulkOperationError(bulkErr)
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeBulkFailure(w, len(operations), index, errResponse)
+		return
+	}
+	var bulkResponse = model.BulkResponse{
+		Applied: true,
+		Results: make([]model.BulkResult, len(changes)),
+	}
+	for index := range changes {
+		var change = &changes[index]
+		h.recordBulkEvent(r, operations[index].Op, change.Before, &change.Todo)
+		h.recordNextOccurrence(r, &change.Todo, change.Next)
+		bulkResponse.Results[index] = model.BulkResult{
+			Index: index,
+			Code:  http.StatusOK,
+			Todo:  &change.Todo,
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(bulkResponse); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+func (h *TodoHandler) recordBulkEvent(r *http.Request, op request.BulkOp, before *model.Todo, todo *model.Todo) {
+	switch op {
+	case request.BulkCreate:
+		h.recordTodoEvent(r, todo.Id, model.TodoCreated, nil, todo)
+	case request.BulkClose, request.BulkReopen:
+		if before.Closed != todo.Closed {
+			h.recordTodoEvent(r, todo.Id, closedAction(todo.Closed), before, todo)
+		}
+	case request.BulkDelete:
+		h.recordTodoEvent(r, todo.Id, model.TodoDeleted, before, nil)
+	case request.BulkMove:
+		h.recordTodoEvent(r, todo.Id, model.TodoUpdated, before, todo)
+	}
+}
+
+// bulkOperationError maps the failure of an operation to its result, the store names
+// the todo, list or tag the operation failed on.
+func bulkOperationError(bulkErr *db.BulkError) model.ResponseError {
+	switch {
+	case errors.Is(bulkErr, db.ErrNotFound):
+		return model.ResponseError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("%s %d not found", bulkErr.Kind, bulkErr.Id),
+		}
+	case errors.Is(bulkErr, db.ErrAccessDenied):
+		return model.ResponseError{
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("Access to %s %d denied", strings.ToLower(bulkErr.Kind), bulkErr.Id),
+		}
+	case errors.Is(bulkErr, db.ErrVersionMismatch):
+		return model.ResponseError{
+			Code:    http.StatusPreconditionFailed,
+			Message: fmt.Sprintf("%s %d was changed by someone else", bulkErr.Kind, bulkErr.Id),
+		}
+	}
+	return model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: "Cannot complete bulk operation",
+	}
+}
+
+// writeBulkFailure reports the operation which failed, all the others were not applied.
+func writeBulkFailure(w http.ResponseWriter, count int, failed int, errResponse model.ResponseError) {
+	var bulkResponse = model.BulkResponse{
+		Results: make([]model.BulkResult, count),
+	}
+	for index := range bulkResponse.Results {
+		bulkResponse.Results[index] = model.BulkResult{
+			Index:   index,
+			Code:    http.StatusFailedDependency,
+			Message: "Not applied",
+		}
+	}
+	bulkResponse.Results[failed].Code = errResponse.Code
+	bulkResponse.Results[failed].Message = errResponse.Message
+	w.WriteHeader(errResponse.Code)
+	if err := json.NewEncoder(w).Encode(bulkResponse); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
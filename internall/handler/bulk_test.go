@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"net/http"
+	"testing"
+)
+
+// bulk posts the operations as the account and returns the per operation result codes.
+func (s *testServer) bulk(t *testing.T, accountId int, status int, operations string) model.BulkResponse {
+	t.Helper()
+	var w = s.do(t, accountId, http.MethodPost, "/todo/bulk", `{"operations":[`+operations+`]}`)
+	expectStatus(t, w, status)
+	var bulkResponse model.BulkResponse
+	decodeResponse(t, w, &bulkResponse)
+	return bulkResponse
+}
+
+func expectBulkCodes(t *testing.T, bulkResponse model.BulkResponse, codes ...int) {
+	t.Helper()
+	if len(bulkResponse.Results) != len(codes) {
+		t.Fatalf("expected %d results, got %+v", len(codes), bulkResponse.Results)
+	}
+	for index, result := range bulkResponse.Results {
+		if result.Index != index || result.Code != codes[index] {
+			t.Fatalf("expected result %d with code %d, got %+v", index, codes[index], result)
+		}
+		if (result.Code == http.StatusOK) != (result.Todo != nil) {
+			t.Fatalf("expected a todo only in applied results, got %+v", result)
+		}
+	}
+	if bulkResponse.Applied != (codes[0] == http.StatusOK) {
+		t.Fatalf("expected applied %v, got %v", codes[0] == http.StatusOK, bulkResponse.Applied)
+	}
+}
+
+func TestBulkFailureRollsBackEarlierOperations(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Buy milk"}`)
+	var bulkResponse = s.bulk(t, alice, http.StatusNotFound, fmt.Sprintf(
+		`{"op":"create","todo":{"title":"Bake bread"}},{"op":"close","todo-id":%d},{"op":"close","todo-id":42},{"op":"delete","todo-id":%d}`,
+		todo.Id, todo.Id,
+	))
+	expectBulkCodes(t, bulkResponse, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency)
+	if message := bulkResponse.Results[2].Message; message != "Todo 42 not found" {
+		t.Fatalf("expected the missing todo to be named, got %q", message)
+	}
+	if current := s.getTodo(t, alice, todo.Id); current.Closed || current.Version != todo.Version {
+		t.Fatalf("expected the close to be rolled back, got %+v", current)
+	}
+	todos, err := s.store.GetTodosBy(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("expected the create to be rolled back, got %+v", todos)
+	}
+}
+
+func TestBulkWithoutAccessIsForbidden(t *testing.T) {
+	var s = newTestServer(t)
+	var alice, bob = s.signUp(t, "alice"), s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path+"/share", `{"user-name":"bob","role":"editor"}`), http.StatusOK)
+	list, err := s.store.CreateListFor(context.Background(), alice, request.ListForm{Name: "Travel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests = []struct {
+		name      string
+		accountId int
+		operation string
+		message   string
+	}{
+		{"another account", s.signUp(t, "carol"), fmt.Sprintf(`{"op":"close","todo-id":%d}`, todo.Id), fmt.Sprintf("Access to todo %d denied", todo.Id)},
+		{"editor deletes", bob, fmt.Sprintf(`{"op":"delete","todo-id":%d}`, todo.Id), fmt.Sprintf("Access to todo %d denied", todo.Id)},
+		{"list of another account", bob, fmt.Sprintf(`{"op":"move","todo-id":%d,"list-id":%d}`, todo.Id, list.Id), fmt.Sprintf("Access to list %d denied", list.Id)},
+	}
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			var bulkResponse = s.bulk(t, req.accountId, http.StatusForbidden, fmt.Sprintf(`{"op":"create","todo":{"title":"Own todo"}},%s`, req.operation))
+			expectBulkCodes(t, bulkResponse, http.StatusFailedDependency, http.StatusForbidden)
+			if message := bulkResponse.Results[1].Message; message != req.message {
+				t.Fatalf("expected message %q, got %q", req.message, message)
+			}
+		})
+	}
+	if current := s.getTodo(t, alice, todo.Id); current.Version != todo.Version {
+		t.Fatalf("expected the todo to stay unchanged, got %+v", current)
+	}
+}
+
+func TestBulkStaleVersionFailsPrecondition(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var first = s.addTodo(t, alice, `{"title":"Buy milk"}`)
+	var second = s.addTodo(t, alice, `{"title":"Bake bread"}`)
+	expectStatus(t, s.do(t, alice, http.MethodPatch, fmt.Sprintf("/todo/%d", second.Id), `{"title":"Bake rolls"}`), http.StatusOK)
+	var operations = fmt.Sprintf(`{"op":"close","todo-id":%d,"version":%d},{"op":"close","todo-id":%d,"version":%d}`,
+		first.Id, first.Version, second.Id, second.Version)
+	var bulkResponse = s.bulk(t, alice, http.StatusPreconditionFailed, operations)
+	expectBulkCodes(t, bulkResponse, http.StatusFailedDependency, http.StatusPreconditionFailed)
+	if current := s.getTodo(t, alice, first.Id); current.Closed {
+		t.Fatal("expected the close of the first todo to be rolled back")
+	}
+	operations = fmt.Sprintf(`{"op":"close","todo-id":%d,"version":%d},{"op":"close","todo-id":%d,"version":%d}`,
+		first.Id, first.Version, second.Id, second.Version+1)
+	expectBulkCodes(t, s.bulk(t, alice, http.StatusOK, operations), http.StatusOK, http.StatusOK)
+}
+
+func TestBulkRecordsEachChangeOfTheSameTodo(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Buy milk"}`)
+	var operations = fmt.Sprintf(`{"op":"close","todo-id":%d},{"op":"reopen","todo-id":%d}`, todo.Id, todo.Id)
+	var bulkResponse = s.bulk(t, alice, http.StatusOK, operations)
+	expectBulkCodes(t, bulkResponse, http.StatusOK, http.StatusOK)
+	if closed := bulkResponse.Results[0].Todo; !closed.Closed || closed.Version != todo.Version+1 {
+		t.Fatalf("expected the first result to be the closed todo, got %+v", closed)
+	}
+	events, err := s.store.GetTodoHistory(context.Background(), todo.Id, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closedBefore = make(map[model.TodoAction]bool)
+	for _, event := range events {
+		var before model.Todo
+		if event.Before != nil && string(event.Before) != "null" {
+			if err := json.Unmarshal(event.Before, &before); err != nil {
+				t.Fatal(err)
+			}
+		}
+		closedBefore[event.Action] = before.Closed
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected created, closed and reopened events, got %d", len(events))
+	}
+	if closed, ok := closedBefore[model.TodoClosed]; !ok || closed {
+		t.Fatal("expected the close to be recorded from the open todo")
+	}
+	if closed, ok := closedBefore[model.TodoReopened]; !ok || !closed {
+		t.Fatal("expected the reopen to be recorded from the closed todo")
+	}
+}
+
+func TestBulkTagOfTrashedTodoIsNotFound(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	tag, err := s.store.CreateTagFor(context.Background(), alice, request.TagForm{Name: "travel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tagTwice = fmt.Sprintf(`{"op":"tag","todo-id":%d,"tag-id":%d},{"op":"tag","todo-id":%d,"tag-id":%d}`, todo.Id, tag.Id, todo.Id, tag.Id)
+	expectBulkCodes(t, s.bulk(t, alice, http.StatusOK, tagTwice), http.StatusOK, http.StatusOK)
+	var operations = fmt.Sprintf(`{"op":"delete","todo-id":%d},{"op":"tag","todo-id":%d,"tag-id":%d}`, todo.Id, todo.Id, tag.Id)
+	expectBulkCodes(t, s.bulk(t, alice, http.StatusNotFound, operations), http.StatusFailedDependency, http.StatusNotFound)
+	expectStatus(t, s.do(t, alice, http.MethodDelete, fmt.Sprintf("/todo/remove/%d", todo.Id), ""), http.StatusOK)
+	if err := s.store.AttachTag(context.Background(), todo.Id, tag.Id); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected a todo in the trash not to be tagged, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// CloseTodoHandler docs
+// @Summary Close todo by id
+// @Description idempotent, closing a closed todo keeps its closed-at and closed-by. Closing an occurrence of a recurring todo generates the next occurrence of its series
+// @Tags todo
+// @ID close-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/close [post]
+func (h *TodoHandler) CloseTodoHandler(w http.ResponseWriter, r *http.Request) {
+	h.setTodoClosed(w, r, true)
+}
+
+// ReopenTodoHandler docs
+// @Summary Reopen todo by id
+// @Description idempotent, reopening an open todo leaves it unchanged
+// @Tags todo
+// @ID reopen-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/reopen [post]
+func (h *TodoHandler) ReopenTodoHandler(w http.ResponseWriter, r *http.Request) {
+	h.setTodoClosed(w, r, false)
+}
+
+func (h *TodoHandler) setTodoClosed(w http.ResponseWriter, r *http.Request, closed bool) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	before, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, before) {
+		return
+	}
+	todo, next, changed, err := h.Todos.SetTodoClosed(r.Context(), todoId, userId, closed, expectedVersion(r, before))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, before)
+		return
+	}
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot change closed state of todo",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Todo %d not found", todoId),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if changed {
+		h.recordTodoEvent(r, todoId, closedAction(closed), before, todo)
+		h.recordNextOccurrence(r, todo, next)
+	}
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// recordNextOccurrence records the creation of the occurrence which the store
+// generated when it closed the recurring todo, next is nil when there is none.
+func (h *TodoHandler) recordNextOccurrence(r *http.Request, todo *model.Todo, next *model.Todo) {
+	if next == nil {
+		return
+	}
+	h.recordTodoEvent(r, next.Id, model.TodoCreated, nil, next)
+	logger.Info("Created next occurrence of recurring todo",
+		zap.Int("todo id", todo.Id),
+		zap.Int("next todo id", next.Id),
+	)
+}
+
+func closedAction(closed bool) model.TodoAction {
+	if closed {
+		return model.TodoClosed
+	}
+	return model.TodoReopened
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// collaborationProtocol is the subprotocol of the channel, browsers which cannot
+	// set the Authorization header offer the access token as a second subprotocol.
+	collaborationProtocol = "todo"
+	// tokenCheckInterval is how often the token of an open channel is authenticated again.
+	tokenCheckInterval = time.Minute
+	// maxCollaborationRequest limits the size of a message from the client in bytes.
+	maxCollaborationRequest = 4096
+	// maxCollaborationTopics limits how many lists and todos a channel may subscribe to.
+	maxCollaborationTopics    = 100
+	collaborationWriteTimeout = 10 * time.Second
+)
+
+const (
+	requestSubscribe   = "subscribe"
+	requestUnsubscribe = "unsubscribe"
+	requestAuth        = "auth"
+
+	messageSubscribed    = "subscribed"
+	messageUnsubscribed  = "unsubscribed"
+	messageAuthenticated = "authenticated"
+	messagePresence      = "presence"
+	messageChange        = "change"
+	messageError         = "error"
+)
+
+type CollaborationHandler struct {
+	Todos    db.TodoStore
+	Lists    db.ListStore
+	Accounts db.AccountStore
+	Changes  *broadcast.Hub
+	Presence *broadcast.Presence
+}
+
+// collaborationRequest is a message from the client, subscriptions name either a list or a todo.
+type collaborationRequest struct {
+	Type   string `json:"type"`
+	ListId int    `json:"list-id"`
+	TodoId int    `json:"todo-id"`
+	Token  string `json:"token"`
+}
+
+type collaborationMessage struct {
+	Type    string             `json:"type"`
+	ListId  int                `json:"list-id,omitempty"`
+	TodoId  int                `json:"todo-id,omitempty"`
+	Viewers []broadcast.Viewer `json:"viewers,omitempty"`
+	Change  json.RawMessage    `json:"change,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+// changeScope is the part of a streamed todo change which tells who it concerns.
+type changeScope struct {
+	TodoId int              `json:"todo-id"`
+	Action model.TodoAction `json:"action"`
+	Before struct {
+		ListId    *int `json:"list-id"`
+		AccountId int  `json:"account-id"`
+	} `json:"before"`
+	After struct {
+		ListId *int `json:"list-id"`
+	} `json:"after"`
+}
+
+// collaborationSession is the state of one open channel.
+type collaborationSession struct {
+	conn    *websocket.Conn
+	userId  int
+	token   string
+	watcher *broadcast.Watcher
+}
+
+// CollaborateHandler docs
+// @Summary Open real-time collaboration channel
+// @Description WebSocket channel. Send {"type":"subscribe","list-id":1} or {"type":"subscribe","todo-id":1} to receive change messages of the list or todo and presence messages listing who else is viewing it, unsubscribe the same way. The access token is checked on upgrade and every minute after, send {"type":"auth","token":"..."} with a refreshed token before it expires. Browsers offer the token as second subprotocol after "todo"
+// @Tags collaboration
+// @ID collaborate-handler
+// @Security ApiKeyAuth
+// @param    Authorization header string false "Authorization"
+// @Param    Sec-WebSocket-Protocol header string false "todo, followed by the access token"
+// @Success  101
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /collaborate [get]
+func (h *CollaborationHandler) CollaborateHandler(w http.ResponseWriter, r *http.Request) {
+	var protocols = websocketProtocols(r)
+	var token = r.Header.Get("Authorization")
+	if len(token) == 0 && len(protocols) == 2 && protocols[0] == collaborationProtocol {
+		token = protocols[1]
+	}
+	userId, err := utility.AuthenticateToken(token)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "token isn't valid",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	account, err := h.Accounts.GetUserById(r.Context(), userId)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve account from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var server = websocket.Server{
+		// the channel authenticates with a token instead of cookies, so other origins cannot abuse it
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if len(config.Protocol) == 0 {
+				return nil
+			}
+			if config.Protocol[0] != collaborationProtocol {
+				return websocket.ErrBadWebSocketProtocol
+			}
+			config.Protocol = []string{collaborationProtocol}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			var session = &collaborationSession{
+				conn:   conn,
+				userId: userId,
+				token:  token,
+				watcher: h.Presence.NewWatcher(broadcast.Viewer{
+					AccountId: account.Id,
+					UserName:  account.UserName,
+				}),
+			}
+			h.collaborate(session)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// collaborate serves the channel until the client leaves, its token expires or it falls behind.
+// Only this goroutine writes to the connection.
+func (h *CollaborationHandler) collaborate(session *collaborationSession) {
+	var conn = session.conn
+	conn.MaxPayloadBytes = maxCollaborationRequest
+	_ = conn.SetDeadline(time.Time{})
+	subscription, _, _ := h.Changes.Subscribe(session.userId, "")
+	defer h.Changes.Unsubscribe(subscription)
+	defer h.Presence.LeaveAll(session.watcher)
+	var requests = make(chan *collaborationRequest)
+	var done = make(chan struct{})
+	defer close(done)
+	go receiveCollaborationRequests(conn, requests, done)
+	var tokenCheck = time.NewTicker(tokenCheckInterval)
+	defer tokenCheck.Stop()
+	var err error
+	for err == nil {
+		select {
+		case request, open := <-requests:
+			if !open {
+				return
+			}
+			err = h.handleCollaborationRequest(session, request)
+		case message, open := <-subscription.C:
+			if !open {
+				_ = sendCollaborationMessage(conn, collaborationMessage{Type: messageError, Message: "channel fell behind, reconnect and subscribe again"})
+				return
+			}
+			err = h.deliverChange(session, message)
+		case <-session.watcher.C:
+			for _, update := range h.Presence.Pending(session.watcher) {
+				var message = topicMessage(messagePresence, update.Topic)
+				message.Viewers = update.Viewers
+				if err = sendCollaborationMessage(conn, message); err != nil {
+					break
+				}
+			}
+		case <-tokenCheck.C:
+			if _, err := utility.AuthenticateToken(session.token); err != nil {
+				_ = sendCollaborationMessage(conn, collaborationMessage{Type: messageError, Message: "token expired"})
+				logger.Info("Closed collaboration channel with expired token", zap.Int("user id", session.userId), zap.Error(err))
+				return
+			}
+		}
+	}
+	logger.Info("Collaboration channel closed", zap.Int("user id", session.userId), zap.Error(err))
+}
+
+// receiveCollaborationRequests reads messages of the client until the connection
+// closes. A message which is no valid request is passed on as nil.
+func receiveCollaborationRequests(conn *websocket.Conn, requests chan<- *collaborationRequest, done <-chan struct{}) {
+	defer close(requests)
+	for {
+		var request = &collaborationRequest{}
+		if err := websocket.JSON.Receive(conn, request); err != nil {
+			var syntaxError *json.SyntaxError
+			var typeError *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxError) && !errors.As(err, &typeError) {
+				return
+			}
+			request = nil
+		}
+		select {
+		case requests <- request:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (h *CollaborationHandler) handleCollaborationRequest(session *collaborationSession, request *collaborationRequest) error {
+	if request == nil {
+		return sendCollaborationMessage(session.conn, collaborationMessage{Type: messageError, Message: "message must be a JSON request"})
+	}
+	switch request.Type {
+	case requestSubscribe:
+		topic, errMessage := h.authorizeTopic(session, request)
+		if len(errMessage) == 0 && !h.Presence.Watching(topic, session.watcher) &&
+			h.Presence.Topics(session.watcher) >= maxCollaborationTopics {
+			errMessage = fmt.Sprintf("channel may not subscribe to more than %d lists and todos", maxCollaborationTopics)
+		}
+		if len(errMessage) != 0 {
+			return sendCollaborationMessage(session.conn, collaborationMessage{Type: messageError, Message: errMessage})
+		}
+		h.Presence.Join(topic, session.watcher)
+		return sendCollaborationMessage(session.conn, topicMessage(messageSubscribed, topic))
+	case requestUnsubscribe:
+		topic, ok := requestTopic(request)
+		if !ok {
+			return sendCollaborationMessage(session.conn, collaborationMessage{Type: messageError, Message: "request must name either list-id or todo-id"})
+		}
+		h.Presence.Leave(topic, session.watcher)
+		return sendCollaborationMessage(session.conn, topicMessage(messageUnsubscribed, topic))
+	case requestAuth:
+		userId, err := utility.AuthenticateToken(request.Token)
+		if err != nil || userId != session.userId {
+			logger.Error("Cannot refresh token of collaboration channel", zap.Int("user id", session.userId), zap.Error(err))
+			return sendCollaborationMessage(session.conn, collaborationMessage{Type: messageError, Message: "token isn't valid"})
+		}
+		session.token = request.Token
+		return sendCollaborationMessage(session.conn, collaborationMessage{Type: messageAuthenticated})
+	default:
+		return sendCollaborationMessage(session.conn, collaborationMessage{
+			Type:    messageError,
+			Message: fmt.Sprintf("type must be one of %s, %s, %s", requestSubscribe, requestUnsubscribe, requestAuth),
+		})
+	}
+}
+
+// authorizeTopic makes sure the caller may watch the requested list or todo,
+// on failure the message for the client is returned.
+func (h *CollaborationHandler) authorizeTopic(session *collaborationSession, request *collaborationRequest) (string, string) {
+	topic, ok := requestTopic(request)
+	if !ok {
+		return "", "request must name either list-id or todo-id"
+	}
+	var r = session.conn.Request()
+	var err error
+	if request.ListId != 0 {
+		_, err = ownedList(r, h.Lists, session.userId, request.ListId)
+	} else {
+		var role model.Role
+		role, err = h.Todos.GetTodoRole(r.Context(), session.userId, request.TodoId)
+		if err == nil && !role.Allows(model.RoleViewer) {
+			err = db.ErrAccessDenied
+		}
+	}
+	var name, kind, id = "Todo", "todo", request.TodoId
+	if request.ListId != 0 {
+		name, kind, id = "List", "list", request.ListId
+	}
+	switch {
+	case err == nil:
+		return topic, ""
+	case errors.Is(err, db.ErrNotFound):
+		return "", fmt.Sprintf("%s %d not found", name, id)
+	case errors.Is(err, db.ErrAccessDenied):
+		return "", fmt.Sprintf("Access to %s %d denied", kind, id)
+	default:
+		logger.Error("Cannot check access of collaboration channel", zap.String("topic", topic), zap.Error(err))
+		return "", fmt.Sprintf("Cannot check access to %s", kind)
+	}
+}
+
+// deliverChange forwards the change if the channel watches its todo or a list the todo
+// is in or leaves. A channel watching a todo it lost access to stops watching it.
+func (h *CollaborationHandler) deliverChange(session *collaborationSession, message broadcast.Message) error {
+	var scope changeScope
+	if err := json.Unmarshal(message.Data, &scope); err != nil {
+		logger.Error("Cannot decode todo change", zap.Error(err))
+		return nil
+	}
+	var todoTopic = todoTopic(scope.TodoId)
+	var watched = h.Presence.Watching(todoTopic, session.watcher)
+	for _, listId := range []*int{scope.Before.ListId, scope.After.ListId} {
+		if listId != nil && h.Presence.Watching(listTopic(*listId), session.watcher) {
+			watched = true
+		}
+	}
+	if !watched {
+		return nil
+	}
+	if err := sendCollaborationMessage(session.conn, collaborationMessage{
+		Type:   messageChange,
+		TodoId: scope.TodoId,
+		Change: message.Data,
+	}); err != nil {
+		return err
+	}
+	if scope.Action == model.TodoRevoked && scope.Before.AccountId == session.userId &&
+		h.Presence.Leave(todoTopic, session.watcher) {
+		var unsubscribed = topicMessage(messageUnsubscribed, todoTopic)
+		unsubscribed.Message = "access revoked"
+		return sendCollaborationMessage(session.conn, unsubscribed)
+	}
+	return nil
+}
+
+func sendCollaborationMessage(conn *websocket.Conn, message collaborationMessage) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(collaborationWriteTimeout)); err != nil {
+		return err
+	}
+	return websocket.JSON.Send(conn, message)
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols = make([]string, 0)
+	for _, value := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if value = strings.TrimSpace(value); len(value) != 0 {
+			protocols = append(protocols, value)
+		}
+	}
+	return protocols
+}
+
+func requestTopic(request *collaborationRequest) (string, bool) {
+	switch {
+	case request.ListId > 0 && request.TodoId == 0:
+		return listTopic(request.ListId), true
+	case request.TodoId > 0 && request.ListId == 0:
+		return todoTopic(request.TodoId), true
+	default:
+		return "", false
+	}
+}
+
+func listTopic(listId int) string {
+	return fmt.Sprintf("list:%d", listId)
+}
+
+func todoTopic(todoId int) string {
+	return fmt.Sprintf("todo:%d", todoId)
+}
+
+// topicMessage builds a message of the type about the list or todo of the topic.
+func topicMessage(messageType string, topic string) collaborationMessage {
+	var message = collaborationMessage{Type: messageType}
+	if _, err := fmt.Sscanf(topic, "list:%d", &message.ListId); err != nil {
+		_, _ = fmt.Sscanf(topic, "todo:%d", &message.TodoId)
+	}
+	return message
+}
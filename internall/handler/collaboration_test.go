@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collaborationServer serves the collaboration channel against the store of the test server.
+func (s *testServer) collaborationServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var secretKey = utility.Config.SecretKey
+	utility.Config.SecretKey = "collaboration test secret"
+	var collaboration = &CollaborationHandler{
+		Todos:    s.store,
+		Lists:    s.store,
+		Accounts: s.store,
+		Changes:  broadcast.NewHub(16),
+		Presence: broadcast.NewPresence(),
+	}
+	var server = httptest.NewServer(http.HandlerFunc(collaboration.CollaborateHandler))
+	t.Cleanup(func() {
+		server.Close()
+		utility.Config.SecretKey = secretKey
+	})
+	return server
+}
+
+func accessToken(t *testing.T, accountId int, userName string) string {
+	t.Helper()
+	token, err := utility.GenerateAccessToken(accountId, userName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func dialCollaboration(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http"), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("Authorization", token)
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+// exchange sends the request and returns the next message of the channel.
+func exchange(t *testing.T, conn *websocket.Conn, request string) collaborationMessage {
+	t.Helper()
+	if request != "" {
+		if _, err := conn.Write([]byte(request)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	var message collaborationMessage
+	if err := websocket.JSON.Receive(conn, &message); err != nil {
+		t.Fatalf("cannot receive message after %s: %v", request, err)
+	}
+	return message
+}
+
+func TestCollaborationAcceptsOnlyAccessTokens(t *testing.T) {
+	var s = newTestServer(t)
+	var server = s.collaborationServer(t)
+	var alice = s.signUp(t, "alice")
+	refreshToken, err := utility.GenerateRefreshToken(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"", "not a token", refreshToken} {
+		var r = httptest.NewRequest(http.MethodGet, server.URL, nil)
+		r.Header.Set("Authorization", token)
+		var w = httptest.NewRecorder()
+		server.Config.Handler.ServeHTTP(w, r)
+		expectStatus(t, w, http.StatusUnauthorized)
+	}
+}
+
+func TestSubscribeRequiresAccessToTopic(t *testing.T) {
+	var s = newTestServer(t)
+	var server = s.collaborationServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	var own = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	var foreign = s.addTodo(t, bob, `{"title":"Secret plan"}`)
+	var conn = dialCollaboration(t, server, accessToken(t, alice, "alice"))
+
+	var message = exchange(t, conn, fmt.Sprintf(`{"type":"subscribe","todo-id":%d}`, foreign.Id))
+	if message.Type != messageError || message.Message != fmt.Sprintf("Access to todo %d denied", foreign.Id) {
+		t.Fatalf("expected access to todo of bob to be denied, got %+v", message)
+	}
+	message = exchange(t, conn, fmt.Sprintf(`{"type":"subscribe","todo-id":%d}`, foreign.Id+100))
+	if message.Type != messageError || message.Message != fmt.Sprintf("Todo %d not found", foreign.Id+100) {
+		t.Fatalf("expected missing todo to be reported, got %+v", message)
+	}
+	message = exchange(t, conn, `{"type":"subscribe","list-id":1,"todo-id":1}`)
+	if message.Type != messageError {
+		t.Fatalf("expected a request naming list and todo to fail, got %+v", message)
+	}
+	message = exchange(t, conn, fmt.Sprintf(`{"type":"subscribe","todo-id":%d}`, own.Id))
+	if message.Type != messageSubscribed || message.TodoId != own.Id {
+		t.Fatalf("expected subscription to own todo, got %+v", message)
+	}
+	message = exchange(t, conn, "")
+	if message.Type != messagePresence || len(message.Viewers) != 1 || message.Viewers[0].UserName != "alice" {
+		t.Fatalf("expected alice to be the only viewer, got %+v", message)
+	}
+}
+
+func TestAuthRefreshRunsFullTokenCheck(t *testing.T) {
+	var s = newTestServer(t)
+	var server = s.collaborationServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	var conn = dialCollaboration(t, server, accessToken(t, alice, "alice"))
+	refreshToken, err := utility.GenerateRefreshToken(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{refreshToken, accessToken(t, bob, "bob")} {
+		var message = exchange(t, conn, fmt.Sprintf(`{"type":"auth","token":%q}`, token))
+		if message.Type != messageError {
+			t.Fatalf("expected token to be rejected, got %+v", message)
+		}
+	}
+	var message = exchange(t, conn, fmt.Sprintf(`{"type":"auth","token":%q}`, accessToken(t, alice, "alice")))
+	if message.Type != messageAuthenticated {
+		t.Fatalf("expected fresh access token to be accepted, got %+v", message)
+	}
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultCommentLimit = 50
+	maxCommentLimit     = 200
+)
+
+// CommentsHandler docs
+// @Summary Get comments of todo
+// @Description comments are ordered oldest first, pass the id of the last received comment as after to get newer ones
+// @Tags todo
+// @ID comments-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    after      query   int     false  "return comments newer than the comment with this id"
+// @Param    limit      query   int     false  "max number of comments" minimum(1) maximum(200) default(50)
+// @Success  200 {array} model.Comment
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/comments [get]
+func (h *TodoHandler) CommentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	afterId, limit, ok := parseCommentRange(w, r)
+	if !ok {
+		return
+	}
+	comments, err := h.Comments.GetComments(r.Context(), todoId, afterId, limit)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve comments",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(comments); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// AddCommentHandler docs
+// @Summary Comment on todo
+// @Description the body is markdown. Every collaborator may comment, @user-name mentions notify collaborators of the todo
+// @Tags todo
+// @ID add-comment-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.CommentForm     true  "form"
+// @Success  200 {object} model.Comment
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/comments [post]
+func (h *TodoHandler) AddCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	commentForm, ok := decodeCommentForm(w, r)
+	if !ok {
+		return
+	}
+	comment, err := h.Comments.CreateCommentFor(r.Context(), todoId, userId, commentForm)
+	if err != nil {
+		writeCommentError(w, err, 0, "Cannot complete operation add comment")
+		return
+	}
+	h.notifyMentions(r, userId, comment, "")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(comment); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// UpdateCommentHandler docs
+// @Summary Edit comment on todo
+// @Description only the author may edit a comment, accounts mentioned for the first time are notified
+// @Tags todo
+// @ID update-comment-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    commentId      path   int     true  "comment id"
+// @Param    body      body   request.CommentForm     true  "form"
+// @Success  200 {object} model.Comment
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/comments/{commentId} [put]
+func (h *TodoHandler) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	current, ok := h.findComment(w, r, todoId)
+	if !ok {
+		return
+	}
+	if current.AuthorId == nil || *current.AuthorId != userId {
+		writeCommentError(w, db.ErrAccessDenied, current.Id, "")
+		return
+	}
+	commentForm, ok := decodeCommentForm(w, r)
+	if !ok {
+		return
+	}
+	comment, err := h.Comments.UpdateCommentBy(r.Context(), current.Id, commentForm)
+	if err != nil {
+		writeCommentError(w, err, current.Id, "Cannot complete operation update comment")
+		return
+	}
+	h.notifyMentions(r, userId, comment, current.Body)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(comment); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// RemoveCommentHandler docs
+// @Summary Remove comment from todo
+// @Description the author and owners of the todo may remove a comment
+// @Tags todo
+// @ID remove-comment-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    commentId      path   int     true  "comment id"
+// @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/comments/{commentId} [delete]
+func (h *TodoHandler) RemoveCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	comment, ok := h.findComment(w, r, todoId)
+	if !ok {
+		return
+	}
+	if comment.AuthorId == nil || *comment.AuthorId != userId {
+		role, err := h.Todos.GetTodoRole(r.Context(), userId, todoId)
+		if err == nil && !role.Allows(model.RoleOwner) {
+			err = db.ErrAccessDenied
+		}
+		if err != nil {
+			writeCommentError(w, err, comment.Id, "Cannot check access to comment")
+			return
+		}
+	}
+	if err := h.Comments.RemoveCommentBy(r.Context(), comment.Id); err != nil {
+		writeCommentError(w, err, comment.Id, "Cannot complete operation remove comment")
+		return
+	}
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Removed comment by %d", comment.Id),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// findComment resolves the comment id from the path within the todo.
+// On failure the response is written and false is returned.
+func (h *TodoHandler) findComment(w http.ResponseWriter, r *http.Request, todoId int) (*model.Comment, bool) {
+	commentId, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve comment id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return nil, false
+	}
+	comment, err := h.Comments.GetCommentBy(r.Context(), todoId, commentId)
+	if err != nil {
+		writeCommentError(w, err, commentId, "Cannot retrieve comment")
+		return nil, false
+	}
+	return comment, true
+}
+
+func parseCommentRange(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	var afterId, limit = 0, defaultCommentLimit
+	var err error
+	if value := r.URL.Query().Get("after"); len(value) != 0 {
+		if afterId, err = strconv.Atoi(value); err != nil || afterId < 1 {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: "after must be a comment id",
+			})
+			logger.Error("Cannot parse after comment id", zap.String("after", value))
+			return 0, 0, false
+		}
+	}
+	if value := r.URL.Query().Get("limit"); len(value) != 0 {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxCommentLimit {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxCommentLimit),
+			})
+			logger.Error("Cannot parse comment limit", zap.String("limit", value))
+			return 0, 0, false
+		}
+	}
+	return afterId, limit, true
+}
+
+func writeCommentError(w http.ResponseWriter, err error, commentId int, message string) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = fmt.Sprintf("Comment %d not found", commentId)
+	case errors.Is(err, db.ErrAccessDenied):
+		errResponse.Code = http.StatusForbidden
+		errResponse.Message = fmt.Sprintf("Access to comment %d denied", commentId)
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
+
+func decodeCommentForm(w http.ResponseWriter, r *http.Request) (request.CommentForm, bool) {
+	var commentForm request.CommentForm
+	if err := json.NewDecoder(r.Body).Decode(&commentForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve comment form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return commentForm, false
+	}
+	if !commentForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Comment must not be empty or longer than 10000 characters",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return commentForm, false
+	}
+	return commentForm, true
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func todoETag(todo *model.Todo) string {
+	return strconv.Quote(strconv.Itoa(todo.Version))
+}
+
+func writeTodoETag(w http.ResponseWriter, todo *model.Todo) {
+	w.Header().Set("ETag", todoETag(todo))
+}
+
+// hasIfMatch reports whether the request makes its change conditional on the todo version.
+func hasIfMatch(r *http.Request) bool {
+	return len(r.Header.Get("If-Match")) != 0
+}
+
+// ifMatches checks the todo against the If-Match header, a request without it always matches.
+// Weak tags never match because If-Match requires the strong comparison.
+func ifMatches(r *http.Request, todo *model.Todo) bool {
+	if !hasIfMatch(r) {
+		return true
+	}
+	var etag = todoETag(todo)
+	for _, value := range r.Header.Values("If-Match") {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkIfMatch makes sure the todo did not change since the caller read it.
+// On failure the response is written and false is returned.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, todo *model.Todo) bool {
+	if ifMatches(r, todo) {
+		return true
+	}
+	writePreconditionFailed(w, todo)
+	return false
+}
+
+// expectedVersion is the version of the todo checked against If-Match, nil without the
+// header. Passing it on to the store fails a change made after the todo was read.
+func expectedVersion(r *http.Request, todo *model.Todo) *int {
+	if !hasIfMatch(r) {
+		return nil
+	}
+	var version = todo.Version
+	return &version
+}
+
+// checkTodoPrecondition is checkIfMatch for handlers which do not load the todo otherwise.
+func (h *TodoHandler) checkTodoPrecondition(w http.ResponseWriter, r *http.Request, todoId int) bool {
+	if !hasIfMatch(r) {
+		return true
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return false
+	}
+	return checkIfMatch(w, r, todo)
+}
+
+func writePreconditionFailed(w http.ResponseWriter, todo *model.Todo) {
+	errResponse := model.ResponseError{
+		Code:    http.StatusPreconditionFailed,
+		Message: fmt.Sprintf("Todo %d was changed by someone else, reload it and retry", todo.Id),
+	}
+	logger.Error(errResponse.Message, zap.String("etag", todoETag(todo)))
+	writeTodoETag(w, todo)
+	writeResponseError(w, errResponse)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"net/http"
+	"testing"
+)
+
+// racingStore changes the todo right after the handler read it, like a concurrent
+// request which lands between the If-Match check and the change.
+type racingStore struct {
+	*db.MemoryStore
+	race bool
+}
+
+func (s *racingStore) GetTodoBy(ctx context.Context, todoId int) (*model.Todo, error) {
+	todo, err := s.MemoryStore.GetTodoBy(ctx, todoId)
+	if err != nil || !s.race {
+		return todo, err
+	}
+	s.race = false
+	var todoForm = request.NewTodoForm(todo)
+	todoForm.Title = "changed concurrently"
+	if _, err := s.MemoryStore.UpdateTodoBy(ctx, todoId, todoForm, nil); err != nil {
+		return todo, err
+	}
+	return todo, nil
+}
+
+func TestChangeAfterIfMatchCheckFailsPrecondition(t *testing.T) {
+	var requests = []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/todo/toggle/%d", ""},
+		{http.MethodPost, "/todo/%d/close", ""},
+		{http.MethodPost, "/todo/%d/transition", `{"status":"done"}`},
+		{http.MethodPut, "/todo/%d/parent", `{"parent-id":null}`},
+		{http.MethodDelete, "/todo/remove/%d", ""},
+	}
+	for _, req := range requests {
+		t.Run(req.method+" "+req.path, func(t *testing.T) {
+			var s = newTestServer(t)
+			var store = &racingStore{MemoryStore: s.store}
+			s.todos.Todos = store
+			var alice = s.signUp(t, "alice")
+			var todo = s.addTodo(t, alice, `{"title":"Buy milk"}`)
+			store.race = true
+			var w = s.do(t, alice, req.method, fmt.Sprintf(req.path, todo.Id), req.body, "If-Match", todoETag(&todo))
+			expectStatus(t, w, http.StatusPreconditionFailed)
+			var current = s.getTodo(t, alice, todo.Id)
+			if current.Closed || current.Version != todo.Version+1 {
+				t.Fatalf("expected only the concurrent change, got %+v", current)
+			}
+		})
+	}
+}
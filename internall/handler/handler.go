@@ -1,9 +1,22 @@
 package handler
 
-import "net/http"
+import (
+	"encoding/json"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"go.uber.org/zap"
+	"net/http"
+)
 
 type CommonHanlder struct{}
 
 func (c CommonHanlder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusBadGateway)
 }
+
+func writeResponseError(w http.ResponseWriter, errResponse model.ResponseError) {
+	w.WriteHeader(errResponse.Code)
+	if err := json.NewEncoder(w).Encode(errResponse); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
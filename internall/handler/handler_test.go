@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer serves the todo routes against a memory store. Requests carry the
+// caller in the context the way the authentication middleware leaves it.
+type testServer struct {
+	store  *db.MemoryStore
+	todos  *TodoHandler
+	router *mux.Router
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	var store = db.NewMemoryStore()
+	var todos = &TodoHandler{
+		Todos:         store,
+		Accounts:      store,
+		Lists:         store,
+		Tags:          store,
+		Events:        store,
+		UndoWindow:    time.Minute,
+		Comments:      store,
+		Notifications: store,
+	}
+	var router = mux.NewRouter()
+	router.HandleFunc("/todo/my/todos", todos.MyTodosHandler).Methods(http.MethodGet)
+	router.HandleFunc("/todo/search", todos.SearchTodosHandler).Methods(http.MethodGet)
+	router.HandleFunc("/todo/add", todos.AddTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/bulk", todos.BulkTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/remove/{id}", todos.RemoveTodoHandler).Methods(http.MethodDelete)
+	router.HandleFunc("/todo/{id}/undo", todos.UndoTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/{id}", todos.GetTodoHandler).Methods(http.MethodGet)
+	router.HandleFunc("/todo/{id}", todos.UpdateTodoHandler).Methods(http.MethodPut)
+	router.HandleFunc("/todo/{id}", todos.PatchTodoHandler).Methods(http.MethodPatch)
+	router.HandleFunc("/todo/toggle/{id}", todos.ToggleTodoHandler).Methods(http.MethodPut)
+	router.HandleFunc("/todo/{id}/close", todos.CloseTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/{id}/transition", todos.TransitionTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/{id}/share", todos.ShareTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/{id}/assignee", todos.AssignTodoHandler).Methods(http.MethodPut)
+	router.HandleFunc("/todo/{id}/parent", todos.SetTodoParentHandler).Methods(http.MethodPut)
+	router.HandleFunc("/todo/{id}/move", todos.MoveTodoHandler).Methods(http.MethodPost)
+	router.HandleFunc("/todo/{id}/tags/{tagId:[0-9]+}", todos.AttachTagHandler).Methods(http.MethodPut)
+	router.HandleFunc("/todo/{id}/attachments", todos.UploadAttachmentHandler).Methods(http.MethodPost)
+	return &testServer{store: store, todos: todos, router: router}
+}
+
+// signUp creates an account and returns its id.
+func (s *testServer) signUp(t *testing.T, userName string) int {
+	t.Helper()
+	account, err := s.store.CreateAccount(context.Background(), request.RegistrationForm{
+		UserName: userName,
+		Password: "password",
+		Email:    userName + "@example.com",
+	})
+	if err != nil {
+		t.Fatalf("cannot create account %s: %v", userName, err)
+	}
+	return account.Id
+}
+
+// do serves the request as the account, header holds name and value pairs.
+func (s *testServer) do(t *testing.T, accountId int, method string, path string, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var r = httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	r = r.WithContext(context.WithValue(r.Context(), utility.UserIdKey, accountId))
+	var w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// addTodo creates a todo from the form body and fails the test otherwise.
+func (s *testServer) addTodo(t *testing.T, accountId int, body string) model.Todo {
+	t.Helper()
+	var w = s.do(t, accountId, http.MethodPost, "/todo/add", body)
+	expectStatus(t, w, http.StatusOK)
+	var todo model.Todo
+	decodeResponse(t, w, &todo)
+	return todo
+}
+
+// getTodo reads the todo as the account and fails the test otherwise.
+func (s *testServer) getTodo(t *testing.T, accountId int, todoId int) model.Todo {
+	t.Helper()
+	var w = s.do(t, accountId, http.MethodGet, fmt.Sprintf("/todo/%d", todoId), "")
+	expectStatus(t, w, http.StatusOK)
+	var todo model.Todo
+	decodeResponse(t, w, &todo)
+	return todo
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("cannot decode response %q: %v", w.Body.String(), err)
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultEventLimit = 50
+	maxEventLimit     = 200
+)
+
+// HistoryHandler docs
+// @Summary Get history of todo
+// @Description events are ordered newest first, pass the id of the last received event as before to get older ones
+// @Tags todo
+// @ID history-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    before      query   int     false  "return events older than the event with this id"
+// @Param    limit      query   int     false  "max number of events" minimum(1) maximum(200) default(50)
+// @Success  200 {array} model.TodoEvent
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/history [get]
+func (h *TodoHandler) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	beforeId, limit, ok := parseEventRange(w, r)
+	if !ok {
+		return
+	}
+	events, err := h.Events.GetTodoHistory(r.Context(), todoId, beforeId, limit)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo history",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// ActivityHandler docs
+// @Summary Get activity feed of my todos
+// @Description changes made by anyone to todos I have access to, newest first
+// @Tags todo
+// @ID activity-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    before      query   int     false  "return events older than the event with this id"
+// @Param    limit      query   int     false  "max number of events" minimum(1) maximum(200) default(50)
+// @Success  200 {array} model.TodoEvent
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/my/activity [get]
+func (h *TodoHandler) ActivityHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	beforeId, limit, ok := parseEventRange(w, r)
+	if !ok {
+		return
+	}
+	events, err := h.Events.GetActivityFeed(r.Context(), userId, beforeId, limit)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve activity feed",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// recordTodoEvent appends an entry to the history of the todo on behalf of the
+// caller. The change is already applied at this point, so failures are only logged.
+func (h *TodoHandler) recordTodoEvent(r *http.Request, todoId int, action model.TodoAction, before interface{}, after interface{}) {
+	userId, _ := r.Context().Value(utility.UserIdKey).(int)
+	if err := h.Events.AddTodoEvent(r.Context(), todoId, userId, action, before, after); err != nil {
+		logger.Error("Cannot record todo event",
+			zap.Int("todo id", todoId),
+			zap.String("action", string(action)),
+			zap.Error(err),
+		)
+	}
+	h.publishTodoChange(r, todoId, userId, action, before, after)
+}
+
+func parseEventRange(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	var beforeId, limit = 0, defaultEventLimit
+	var err error
+	if value := r.URL.Query().Get("before"); len(value) != 0 {
+		if beforeId, err = strconv.Atoi(value); err != nil || beforeId < 1 {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: "before must be an event id",
+			})
+			logger.Error("Cannot parse before event id", zap.String("before", value))
+			return 0, 0, false
+		}
+	}
+	if value := r.URL.Query().Get("limit"); len(value) != 0 {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxEventLimit {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxEventLimit),
+			})
+			logger.Error("Cannot parse event limit", zap.String("limit", value))
+			return 0, 0, false
+		}
+	}
+	return beforeId, limit, true
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type ListHandler struct {
+	Lists db.ListStore
+}
+
+// MyListsHandler docs
+// @Summary Get my lists
+// @Tags list
+// @ID my-lists-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    archived      query   bool     false  "include archived lists"
+// @Success  200 {array} model.List
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists [get]
+func (h *ListHandler) MyListsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+	lists, err := h.Lists.GetListsBy(r.Context(), userId, includeArchived)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve lists",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(lists); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// AddListHandler docs
+// @Summary Create new list
+// @Tags list
+// @ID add-list-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    body      body   request.ListForm     true  "form"
+// @Success  200 {object} model.List
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists [post]
+func (h *ListHandler) AddListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	listForm, ok := decodeListForm(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Lists.CreateListFor(r.Context(), userId, listForm)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation add list",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// GetListHandler docs
+// @Summary Get list by id
+// @Tags list
+// @ID get-list-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Success  200 {object} model.List
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id} [get]
+func (h *ListHandler) GetListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// UpdateListHandler docs
+// @Summary Update name, colour and archived flag of list by id
+// @Tags list
+// @ID update-list-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Param    body      body   request.ListForm     true  "form"
+// @Success  200 {object} model.List
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id} [put]
+func (h *ListHandler) UpdateListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	listForm, ok := decodeListForm(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Lists.UpdateListBy(r.Context(), list.Id, listForm)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation update list",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// RemoveListHandler docs
+// @Summary Remove list by id
+// @Description todos of the removed list are kept and detached from it, they move into the default workflow
+// @Tags list
+// @ID remove-list-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id} [delete]
+func (h *ListHandler) RemoveListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Lists.RemoveListBy(r.Context(), list.Id); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation remove list",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Removed list by %d", list.Id),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// ListTodosHandler docs
+// @Summary Get todos assigned to list
+// @Tags list
+// @ID list-todos-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Success  200 {array} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id}/todos [get]
+func (h *ListHandler) ListTodosHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	todos, err := h.Lists.GetTodosByList(r.Context(), userId, list.Id)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo models",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todos); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// authorizeList resolves the list from the path and makes sure it belongs to
+// the caller. On failure the response is written and false is returned.
+func (h *ListHandler) authorizeList(w http.ResponseWriter, r *http.Request) (int, *model.List, bool) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		})
+		logger.Error("Cannot retrieve user id")
+		return 0, nil, false
+	}
+	listId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve list id",
+		})
+		logger.Error("Cannot retrieve list id", zap.Error(err))
+		return 0, nil, false
+	}
+	list, err := ownedList(r, h.Lists, userId, listId)
+	if err != nil {
+		writeListAccessError(w, err, listId)
+		return 0, nil, false
+	}
+	return userId, list, true
+}
+
+func ownedList(r *http.Request, lists db.ListStore, userId int, listId int) (*model.List, error) {
+	list, err := lists.GetListBy(r.Context(), listId)
+	if err != nil {
+		return nil, err
+	}
+	if list.AccountId != userId {
+		return nil, db.ErrAccessDenied
+	}
+	return list, nil
+}
+
+func writeListAccessError(w http.ResponseWriter, err error, listId int) {
+	var errResponse model.ResponseError
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		errResponse = model.ResponseError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("List %d not found", listId),
+		}
+	case errors.Is(err, db.ErrAccessDenied):
+		errResponse = model.ResponseError{
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("Access to list %d denied", listId),
+		}
+	default:
+		errResponse = model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot check access to list",
+		}
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
+
+func decodeListForm(w http.ResponseWriter, r *http.Request) (request.ListForm, bool) {
+	var listForm request.ListForm
+	if err := json.NewDecoder(r.Body).Decode(&listForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve list form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return listForm, false
+	}
+	if !listForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "List name must not be empty and colour must look like #rrggbb",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return listForm, false
+	}
+	return listForm, true
+}
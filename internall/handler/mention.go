@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"go.uber.org/zap"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxMentions caps how many accounts a single comment may notify.
+const maxMentions = 20
+
+var (
+	fencedCodePattern = regexp.MustCompile("(?s)```.*?(```|$)")
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+	// mentionPattern does not match inside words, so e-mail addresses are no mentions.
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@/-])@([\p{L}\p{N}_.-]+)`)
+)
+
+// mentions returns the user names mentioned in a markdown body in order of
+// appearance. Mentions inside code spans and code blocks are ignored.
+func mentions(body string) []string {
+	body = fencedCodePattern.ReplaceAllString(body, " ")
+	body = inlineCodePattern.ReplaceAllString(body, " ")
+	var userNames = make([]string, 0)
+	var seen = make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		var userName = strings.TrimRight(match[1], ".-")
+		if len(userName) == 0 || seen[userName] {
+			continue
+		}
+		seen[userName] = true
+		userNames = append(userNames, userName)
+		if len(userNames) == maxMentions {
+			break
+		}
+	}
+	return userNames
+}
+
+// notifyMentions notifies accounts mentioned in the comment which were not
+// mentioned in the previous body already. Accounts without access to the todo
+// are skipped, so a mention never reveals the todo.
+func (h *TodoHandler) notifyMentions(r *http.Request, userId int, comment *model.Comment, previousBody string) {
+	var previous = make(map[string]bool)
+	for _, userName := range mentions(previousBody) {
+		previous[userName] = true
+	}
+	for _, userName := range mentions(comment.Body) {
+		if previous[userName] {
+			continue
+		}
+		account, err := h.Accounts.GetUserByUserName(r.Context(), userName)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error("Cannot resolve mentioned account", zap.String("user name", userName), zap.Error(err))
+			continue
+		}
+		if account.Id == userId {
+			continue
+		}
+		if _, err := h.Todos.GetTodoRole(r.Context(), account.Id, comment.TodoId); err != nil {
+			if !errors.Is(err, db.ErrNotFound) && !errors.Is(err, db.ErrAccessDenied) {
+				logger.Error("Cannot check access of mentioned account", zap.Int("account id", account.Id), zap.Error(err))
+			}
+			continue
+		}
+		if err := h.Notifications.AddNotification(r.Context(), account.Id, model.NotificationMentioned, comment.TodoId, userId, comment); err != nil {
+			logger.Error("Cannot notify mentioned account",
+				zap.Int("account id", account.Id),
+				zap.Int("comment id", comment.Id),
+				zap.Error(err),
+			)
+		}
+	}
+}
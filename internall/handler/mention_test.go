@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	var bodies = []struct {
+		name      string
+		body      string
+		userNames []string
+	}{
+		{"none", "no mention here", []string{}},
+		{"start of body", "@bob please check", []string{"bob"}},
+		{"in order of appearance", "ask @carol, then @bob", []string{"carol", "bob"}},
+		{"repeated", "@bob and @bob again", []string{"bob"}},
+		{"trailing punctuation", "thanks @bob. and @carol-", []string{"bob", "carol"}},
+		{"in parentheses", "(cc @bob)", []string{"bob"}},
+		{"dotted name", "@bob.smith: done", []string{"bob.smith"}},
+		{"e-mail address", "write to bob@example.com", []string{}},
+		{"url path", "see https://example.com/@bob", []string{}},
+		{"bare at sign", "meet @ noon, @.-", []string{}},
+		{"inline code", "`@bob` is for @carol", []string{"carol"}},
+		{"fenced code", "```\n@bob\n```\n@carol", []string{"carol"}},
+		{"unterminated fence", "@carol\n```\n@bob", []string{"carol"}},
+	}
+	for _, body := range bodies {
+		t.Run(body.name, func(t *testing.T) {
+			var userNames = mentions(body.body)
+			if fmt.Sprint(userNames) != fmt.Sprint(body.userNames) {
+				t.Fatalf("expected %v, got %v", body.userNames, userNames)
+			}
+		})
+	}
+}
+
+func TestMentionsAreCapped(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < maxMentions+5; i++ {
+		fmt.Fprintf(&body, "@user%d @user0 ", i)
+	}
+	var userNames = mentions(body.String())
+	if len(userNames) != maxMentions {
+		t.Fatalf("expected %d mentions, got %d", maxMentions, len(userNames))
+	}
+	if userNames[maxMentions-1] != fmt.Sprintf("user%d", maxMentions-1) {
+		t.Fatalf("expected the first %d mentions, got %v", maxMentions, userNames)
+	}
+}
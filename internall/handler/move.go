@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// MoveTodoHandler docs
+// @Summary Move todo in my manual order
+// @Description places the todo right before or right after another of my todos, exactly one of before and after must be set. The order is personal, collaborators keep their own
+// @Tags todo
+// @ID move-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.MoveForm     true  "form"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/move [post]
+func (h *TodoHandler) MoveTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	var moveForm request.MoveForm
+	if err := json.NewDecoder(r.Body).Decode(&moveForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve move form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !moveForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Move form requires exactly one of before and after",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	anchorId, after := moveForm.Anchor()
+	if anchorId == todoId {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Todo cannot be moved next to itself",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if _, err := h.Todos.GetTodoRole(r.Context(), userId, anchorId); err != nil {
+		writeMoveError(w, err, anchorId)
+		return
+	}
+	position, err := h.Todos.MoveTodo(r.Context(), userId, todoId, anchorId, after)
+	if err != nil {
+		writeMoveError(w, err, anchorId)
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	todo.Position = position
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+func writeMoveError(w http.ResponseWriter, err error, anchorId int) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: "Cannot complete operation move todo",
+	}
+	// An anchor of another account is reported like a missing one, it is not among
+	// the caller's todos either way and its existence stays hidden.
+	if errors.Is(err, db.ErrNotFound) || errors.Is(err, db.ErrAccessDenied) {
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = fmt.Sprintf("Todo %d not found", anchorId)
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
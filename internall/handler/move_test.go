@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMoveNextToTodoOfAnotherAccountIsNotFound(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var bob = s.signUp(t, "bob")
+	var todo = s.addTodo(t, bob, `{"title":"Buy milk"}`)
+	var foreign = s.addTodo(t, alice, `{"title":"Secret plan"}`)
+	var path = fmt.Sprintf("/todo/%d/move", todo.Id)
+	var foreignAnchor = s.do(t, bob, http.MethodPost, path, fmt.Sprintf(`{"before":%d}`, foreign.Id))
+	expectStatus(t, foreignAnchor, http.StatusNotFound)
+	var missingAnchor = s.do(t, bob, http.MethodPost, path, fmt.Sprintf(`{"before":%d}`, foreign.Id+100))
+	expectStatus(t, missingAnchor, http.StatusNotFound)
+}
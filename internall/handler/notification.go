@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+type NotificationHandler struct {
+	Notifications db.NotificationStore
+}
+
+// MyNotificationsHandler docs
+// @Summary Get my notifications
+// @Description notifications are ordered newest first, pass the id of the last received notification as before to get older ones
+// @Tags notification
+// @ID my-notifications-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    before      query   int     false  "return notifications older than the notification with this id"
+// @Param    limit      query   int     false  "max number of notifications" minimum(1) maximum(200) default(50)
+// @Param    unread      query   bool     false  "return only unread notifications"
+// @Success  200 {array} model.Notification
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /notifications [get]
+func (h *NotificationHandler) MyNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := notificationUserId(w, r)
+	if !ok {
+		return
+	}
+	beforeId, limit, unreadOnly, ok := parseNotificationQuery(w, r)
+	if !ok {
+		return
+	}
+	notifications, err := h.Notifications.GetNotifications(r.Context(), userId, beforeId, limit, unreadOnly)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve notifications",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(notifications); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// UnreadNotificationsHandler docs
+// @Summary Count my unread notifications
+// @Tags notification
+// @ID unread-notifications-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Success  200 {object} model.UnreadNotifications
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /notifications/unread-count [get]
+func (h *NotificationHandler) UnreadNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := notificationUserId(w, r)
+	if !ok {
+		return
+	}
+	count, err := h.Notifications.CountUnreadNotifications(r.Context(), userId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot count unread notifications",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(model.UnreadNotifications{Count: count}); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// ReadNotificationHandler docs
+// @Summary Mark my notification as read
+// @Description idempotent, marking a read notification keeps its read-at
+// @Tags notification
+// @ID read-notification-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "notification id"
+// @Success  200 {object} model.Notification
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /notifications/{id}/read [post]
+func (h *NotificationHandler) ReadNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := notificationUserId(w, r)
+	if !ok {
+		return
+	}
+	notificationId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve notification id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	notification, err := h.Notifications.MarkNotificationRead(r.Context(), userId, notificationId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot mark notification as read",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Notification %d not found", notificationId),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(notification); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// ReadAllNotificationsHandler docs
+// @Summary Mark all my notifications as read
+// @Tags notification
+// @ID read-all-notifications-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Success  200 {object} model.Response
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /notifications/read [post]
+func (h *NotificationHandler) ReadAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := notificationUserId(w, r)
+	if !ok {
+		return
+	}
+	marked, err := h.Notifications.MarkAllNotificationsRead(r.Context(), userId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot mark notifications as read",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Marked %d notifications as read", marked),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+func notificationUserId(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+	}
+	return userId, ok
+}
+
+func parseNotificationQuery(w http.ResponseWriter, r *http.Request) (int, int, bool, bool) {
+	var beforeId, limit = 0, defaultNotificationLimit
+	var unreadOnly bool
+	var err error
+	if value := r.URL.Query().Get("before"); len(value) != 0 {
+		if beforeId, err = strconv.Atoi(value); err != nil || beforeId < 1 {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: "before must be a notification id",
+			})
+			logger.Error("Cannot parse before notification id", zap.String("before", value))
+			return 0, 0, false, false
+		}
+	}
+	if value := r.URL.Query().Get("limit"); len(value) != 0 {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxNotificationLimit {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxNotificationLimit),
+			})
+			logger.Error("Cannot parse notification limit", zap.String("limit", value))
+			return 0, 0, false, false
+		}
+	}
+	if value := r.URL.Query().Get("unread"); len(value) != 0 {
+		if unreadOnly, err = strconv.ParseBool(value); err != nil {
+			writeResponseError(w, model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: "unread must be true or false",
+			})
+			logger.Error("Cannot parse unread flag", zap.String("unread", value))
+			return 0, 0, false, false
+		}
+	}
+	return beforeId, limit, unreadOnly, true
+}
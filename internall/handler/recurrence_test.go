@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const monthlyTodo = `{"title":"Pay rent","due-at":"2027-01-31T09:00:00Z","recurrence":{"frequency":"monthly"}}`
+
+// occurrences returns the todos of the account in the series of the todo by occurrence.
+func (s *testServer) occurrences(t *testing.T, accountId int, seriesId int) map[int]model.Todo {
+	t.Helper()
+	todos, err := s.store.GetTodosBy(context.Background(), accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var series = make(map[int]model.Todo)
+	for _, todo := range todos {
+		if todo.Id == seriesId || (todo.SeriesId != nil && *todo.SeriesId == seriesId) {
+			series[todo.Occurrence] = todo
+		}
+	}
+	return series
+}
+
+func TestClosingRecurringTodoCreatesNextOccurrenceOnce(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, monthlyTodo)
+	var path = fmt.Sprintf("/todo/%d/close", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path, ""), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path, ""), http.StatusOK)
+	var series = s.occurrences(t, alice, todo.Id)
+	if len(series) != 2 {
+		t.Fatalf("expected the closed todo and one next occurrence, got %d todos", len(series))
+	}
+	var next = series[2]
+	var due = time.Date(2027, time.February, 28, 9, 0, 0, 0, time.UTC)
+	if next.Closed || next.DueAt == nil || !next.DueAt.Equal(due) {
+		t.Fatalf("expected open occurrence due %v, got %+v", due, next)
+	}
+}
+
+func TestMonthEndSeriesDoesNotDrift(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, monthlyTodo)
+	for occurrence := 1; occurrence <= 2; occurrence++ {
+		var current = s.occurrences(t, alice, todo.Id)[occurrence]
+		expectStatus(t, s.do(t, alice, http.MethodPut, fmt.Sprintf("/todo/toggle/%d", current.Id), ""), http.StatusOK)
+	}
+	var third = s.occurrences(t, alice, todo.Id)[3]
+	var due = time.Date(2027, time.March, 31, 9, 0, 0, 0, time.UTC)
+	if third.DueAt == nil || !third.DueAt.Equal(due) {
+		t.Fatalf("expected third occurrence due %v, got %v", due, third.DueAt)
+	}
+}
+
+func TestBulkCloseCreatesNextOccurrence(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, monthlyTodo)
+	var body = fmt.Sprintf(`{"operations":[{"op":"close","todo-id":%d}]}`, todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, "/todo/bulk", body), http.StatusOK)
+	if next, ok := s.occurrences(t, alice, todo.Id)[2]; !ok || next.Closed {
+		t.Fatalf("expected an open next occurrence after bulk close, got %+v", next)
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// ShareTodoHandler docs
+// @Summary Share todo with another account
+// @Description grant or change a role (owner, editor, viewer) of an account on the todo
+// @Tags todo
+// @ID share-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.ShareForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Collaborator
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/share [post]
+func (h *TodoHandler) ShareTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleOwner)
+	if !ok || !h.checkTodoPrecondition(w, r, todoId) {
+		return
+	}
+	var shareForm request.ShareForm
+	if err := json.NewDecoder(r.Body).Decode(&shareForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve share form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !shareForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Share form requires user name and one of roles owner, editor, viewer",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	account, err := h.Accounts.GetUserByUserName(r.Context(), shareForm.UserName)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve account from db",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Account %s not found", shareForm.UserName),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if err := h.Todos.ShareTodo(r.Context(), todoId, account.Id, shareForm.Role); err != nil {
+		writeCollaboratorError(w, err, "Cannot complete operation share todo item")
+		return
+	}
+	var collaborator = model.Collaborator{
+		AccountId: account.Id,
+		UserName:  account.UserName,
+		Role:      shareForm.Role,
+	}
+	h.recordTodoEvent(r, todoId, model.TodoShared, nil, collaborator)
+	h.notifyShare(r, todoId, collaborator)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(collaborator); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// CollaboratorsHandler docs
+// @Summary List accounts which have access to todo
+// @Tags todo
+// @ID collaborators-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Success  200 {array} model.Collaborator
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/collaborators [get]
+func (h *TodoHandler) CollaboratorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	collaborators, err := h.Todos.GetCollaborators(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve collaborators",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(collaborators); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// RevokeCollaboratorHandler docs
+// @Summary Revoke access of account to todo
+// @Description owners may revoke anyone, other collaborators may only revoke themselves
+// @Tags todo
+// @ID revoke-collaborator-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    accountId      path   int     true  "account id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/collaborators/{accountId} [delete]
+func (h *TodoHandler) RevokeCollaboratorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	accountId, err := strconv.Atoi(mux.Vars(r)["accountId"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve account id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var required = model.RoleOwner
+	if userId, ok := r.Context().Value(utility.UserIdKey).(int); ok && userId == accountId {
+		required = model.RoleViewer
+	}
+	_, todoId, ok := h.authorizeTodo(w, r, required)
+	if !ok || !h.checkTodoPrecondition(w, r, todoId) {
+		return
+	}
+	collaborators, err := h.Todos.GetCollaborators(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve collaborators",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if err := h.Todos.RevokeTodoAccess(r.Context(), todoId, accountId); err != nil {
+		writeCollaboratorError(w, err, "Cannot complete operation revoke access to todo item")
+		return
+	}
+	for _, collaborator := range collaborators {
+		if collaborator.AccountId == accountId {
+			h.recordTodoEvent(r, todoId, model.TodoRevoked, collaborator, nil)
+		}
+	}
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Revoked access of account %d to todo %d", accountId, todoId),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// notifyShare tells the account a todo was shared with it, sharing with oneself
+// is not worth a notification.
+func (h *TodoHandler) notifyShare(r *http.Request, todoId int, collaborator model.Collaborator) {
+	userId, _ := r.Context().Value(utility.UserIdKey).(int)
+	if collaborator.AccountId == userId {
+		return
+	}
+	if err := h.Notifications.AddNotification(r.Context(), collaborator.AccountId, model.NotificationShared, todoId, userId, collaborator); err != nil {
+		logger.Error("Cannot notify account about share",
+			zap.Int("todo id", todoId),
+			zap.Int("account id", collaborator.AccountId),
+			zap.Error(err),
+		)
+	}
+}
+
+func writeCollaboratorError(w http.ResponseWriter, err error, message string) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = "Collaborator not found"
+	case errors.Is(err, db.ErrLastOwner):
+		errResponse.Code = http.StatusConflict
+		errResponse.Message = err.Error()
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
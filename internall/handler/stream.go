@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// streamHeartbeat is how often an idle stream sends a comment, so proxies
+	// keep the connection open and dead clients are noticed.
+	streamHeartbeat = 15 * time.Second
+	// streamWriteTimeout bounds a single write to the client.
+	streamWriteTimeout = 10 * time.Second
+	// streamRetry is how long browsers wait before reconnecting, in milliseconds.
+	streamRetry = 3000
+	// streamReset tells the client its Last-Event-ID cannot be resumed and it has to reload its todos.
+	streamReset = "reset"
+)
+
+type StreamHandler struct {
+	Changes *broadcast.Hub
+}
+
+// EventsHandler docs
+// @Summary Stream changes of my todos
+// @Description server-sent events of changes to todos the caller has access to, the event name is the action of the change. Reconnecting with Last-Event-ID replays missed changes, a reset event is sent when they are no longer kept
+// @Tags todo
+// @ID events-handler
+// @Produce  text/event-stream
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    Last-Event-ID      header   string     false  "id of the last received event"
+// @Success  200 {object} model.TodoChange
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /events [get]
+func (h *StreamHandler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		w.Header().Add("Content-Type", "application/json")
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var lastId = r.Header.Get("Last-Event-ID")
+	subscription, replay, complete := h.Changes.Subscribe(userId, lastId)
+	defer h.Changes.Unsubscribe(subscription)
+	conn, stream, ok := hijackStream(w)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+	var gone = make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, stream)
+		close(gone)
+	}()
+	var err = writeStream(conn, stream, "HTTP/1.1 200 OK\r\n"+
+		"Content-Type: text/event-stream\r\n"+
+		"Cache-Control: no-cache\r\n"+
+		"Connection: close\r\n"+
+		"X-Accel-Buffering: no\r\n\r\n"+
+		fmt.Sprintf("retry: %d\n\n", streamRetry))
+	if err == nil && !complete {
+		err = writeStream(conn, stream, fmt.Sprintf("event: %s\ndata: {}\n\n", streamReset))
+	}
+	for _, message := range replay {
+		if err != nil {
+			break
+		}
+		err = writeStreamMessage(conn, stream, message)
+	}
+	var heartbeat = time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for err == nil {
+		select {
+		case message, open := <-subscription.C:
+			if !open {
+				logger.Info("Event stream fell behind, closing it", zap.Int("user id", userId))
+				return
+			}
+			err = writeStreamMessage(conn, stream, message)
+		case <-heartbeat.C:
+			err = writeStream(conn, stream, ": ping\n\n")
+		case <-gone:
+			return
+		}
+	}
+	logger.Info("Event stream closed", zap.Int("user id", userId), zap.Error(err))
+}
+
+// publishTodoChange streams the change to every account with access to the todo,
+// an account which just lost access still learns about its revocation.
+func (h *TodoHandler) publishTodoChange(r *http.Request, todoId int, userId int, action model.TodoAction, before interface{}, after interface{}) {
+	if h.Changes == nil {
+		return
+	}
+	collaborators, err := h.Todos.GetCollaborators(r.Context(), todoId)
+	if err != nil {
+		logger.Error("Cannot retrieve recipients of todo change", zap.Int("todo id", todoId), zap.Error(err))
+		return
+	}
+	var recipients = make([]int, 0, len(collaborators)+1)
+	for _, collaborator := range collaborators {
+		recipients = append(recipients, collaborator.AccountId)
+	}
+	if revoked, ok := before.(model.Collaborator); ok && action == model.TodoRevoked {
+		recipients = append(recipients, revoked.AccountId)
+	}
+	var change = model.TodoChange{
+		TodoId:    todoId,
+		ActorId:   userId,
+		Action:    action,
+		CreatedOn: time.Now(),
+	}
+	if change.Before, err = json.Marshal(before); err == nil {
+		change.After, err = json.Marshal(after)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(change)
+	}
+	if err != nil {
+		logger.Error("Cannot encode todo change", zap.Int("todo id", todoId), zap.Error(err))
+		return
+	}
+	h.Changes.Publish(string(action), data, recipients)
+}
+
+// hijackStream takes over the connection, so the stream is not cut off by the
+// write timeout of the server. On failure the response is written and false is returned.
+func hijackStream(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, bool) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.Header().Add("Content-Type", "application/json")
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Connection does not support streaming",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return nil, nil, false
+	}
+	conn, stream, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("Cannot take over connection", zap.Error(err))
+		return nil, nil, false
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, stream, true
+}
+
+func writeStreamMessage(conn net.Conn, stream *bufio.ReadWriter, message broadcast.Message) error {
+	return writeStream(conn, stream, fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", message.Id, message.Event, message.Data))
+}
+
+func writeStream(conn net.Conn, stream *bufio.ReadWriter, text string) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+	if _, err := stream.WriteString(text); err != nil {
+		return err
+	}
+	return stream.Flush()
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamEvent is an event read from the stream, the retry hint is skipped.
+type streamEvent struct {
+	id    string
+	event string
+}
+
+// openStream connects to the event stream of the account, resuming after lastId unless it is empty.
+func openStream(t *testing.T, changes *broadcast.Hub, accountId int, lastId string) (*bufio.Reader, func()) {
+	t.Helper()
+	var stream = &StreamHandler{Changes: changes}
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream.EventsHandler(w, r.WithContext(context.WithValue(r.Context(), utility.UserIdKey, accountId)))
+	}))
+	r, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("cannot create request: %v", err)
+	}
+	if len(lastId) != 0 {
+		r.Header.Set("Last-Event-ID", lastId)
+	}
+	var client = &http.Client{Timeout: 5 * time.Second}
+	response, err := client.Do(r)
+	if err != nil {
+		t.Fatalf("cannot open stream: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	return bufio.NewReader(response.Body), func() {
+		_ = response.Body.Close()
+		server.Close()
+	}
+}
+
+// readEvents reads count events from the stream.
+func readEvents(t *testing.T, reader *bufio.Reader, count int) []streamEvent {
+	t.Helper()
+	var events = make([]streamEvent, 0, count)
+	var current streamEvent
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("cannot read stream after %v: %v", events, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case len(line) == 0 && len(current.event) != 0:
+			events = append(events, current)
+			current = streamEvent{}
+		}
+	}
+	return events
+}
+
+func TestStreamReplaysFromLastEventId(t *testing.T) {
+	var s = newTestServer(t)
+	var changes = broadcast.NewHub(2)
+	s.todos.Changes = changes
+	var alice = s.signUp(t, "alice")
+	reader, closeStream := openStream(t, changes, alice, "")
+	for i := 1; i <= 4; i++ {
+		s.addTodo(t, alice, fmt.Sprintf(`{"title":"Todo %d"}`, i))
+	}
+	var live = readEvents(t, reader, 4)
+	closeStream()
+	for _, event := range live {
+		if event.event != "created" {
+			t.Fatalf("expected only created events, got %v", live)
+		}
+	}
+	var resumes = []struct {
+		name   string
+		lastId string
+		events []streamEvent
+	}{
+		{"kept id", live[2].id, []streamEvent{live[3]}},
+		{"last evicted id", live[1].id, []streamEvent{live[2], live[3]}},
+		{"older evicted id", live[0].id, []streamEvent{{event: streamReset}, live[2], live[3]}},
+		{"unknown id", "unknown", []streamEvent{{event: streamReset}}},
+	}
+	for _, resume := range resumes {
+		t.Run(resume.name, func(t *testing.T) {
+			reader, closeStream := openStream(t, changes, alice, resume.lastId)
+			defer closeStream()
+			if events := readEvents(t, reader, len(resume.events)); !reflect.DeepEqual(events, resume.events) {
+				t.Fatalf("expected %v, got %v", resume.events, events)
+			}
+		})
+	}
+}
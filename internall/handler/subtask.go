@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AddSubtaskHandler docs
+// @Summary Add subtask to todo
+// @Description the subtask is appended after the existing subtasks and shared with everyone who has access to the parent
+// @Tags todo
+// @ID add-subtask-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "parent todo id"
+// @Param    body      body   request.TodoForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/subtasks [post]
+func (h *TodoHandler) AddSubtaskHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, parentId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok || !h.checkTodoPrecondition(w, r, parentId) {
+		return
+	}
+	var todoForm request.TodoForm
+	if err := json.NewDecoder(r.Body).Decode(&todoForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve todo form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !todoForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: invalidTodoFormMessage,
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !h.checkTodoList(w, r, userId, todoForm.ListId, nil) {
+		return
+	}
+	todo, err := h.Todos.CreateSubtaskFor(r.Context(), parentId, todoForm)
+	if err != nil {
+		writeSubtaskError(w, err, "Cannot complete operation add subtask")
+		return
+	}
+	h.recordTodoEvent(r, todo.Id, model.TodoCreated, nil, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// SubtasksHandler docs
+// @Summary Get subtasks of todo in their order
+// @Tags todo
+// @ID subtasks-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "parent todo id"
+// @Success  200 {array} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/subtasks [get]
+func (h *TodoHandler) SubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, parentId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	subtasks, err := h.Todos.GetSubtasks(r.Context(), parentId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve subtasks",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(subtasks); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// ReorderSubtasksHandler docs
+// @Summary Reorder subtasks of todo
+// @Description subtask-ids must list every subtask of the todo exactly once in the new order
+// @Tags todo
+// @ID reorder-subtasks-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "parent todo id"
+// @Param    body      body   request.SubtaskOrderForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {array} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/subtasks/order [put]
+func (h *TodoHandler) ReorderSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, parentId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok || !h.checkTodoPrecondition(w, r, parentId) {
+		return
+	}
+	var orderForm request.SubtaskOrderForm
+	if err := json.NewDecoder(r.Body).Decode(&orderForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve subtask order form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	before, err := h.Todos.GetSubtasks(r.Context(), parentId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve subtasks",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if err := h.Todos.ReorderSubtasks(r.Context(), parentId, orderForm.SubtaskIds); err != nil {
+		writeSubtaskError(w, err, "Cannot complete operation reorder subtasks")
+		return
+	}
+	subtasks, err := h.Todos.GetSubtasks(r.Context(), parentId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve subtasks",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, parentId, model.TodoReordered, todoIds(before), todoIds(subtasks))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(subtasks); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// SetTodoParentHandler docs
+// @Summary Move todo under another parent
+// @Description null parent-id turns the todo into a top-level todo, a parent inside the todo's own subtree is rejected
+// @Tags todo
+// @ID set-todo-parent-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.ParentForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/parent [put]
+func (h *TodoHandler) SetTodoParentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	var parentForm request.ParentForm
+	if err := json.NewDecoder(r.Body).Decode(&parentForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve parent form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if parentForm.ParentId != nil {
+		role, err := h.Todos.GetTodoRole(r.Context(), userId, *parentForm.ParentId)
+		if err == nil && !role.Allows(model.RoleEditor) {
+			err = db.ErrAccessDenied
+		}
+		if err != nil {
+			writeSubtaskError(w, err, "Cannot check access to parent todo")
+			return
+		}
+	}
+	before, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, before) {
+		return
+	}
+	err = h.Todos.SetTodoParent(r.Context(), todoId, parentForm.ParentId, expectedVersion(r, before))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, before)
+		return
+	}
+	if err != nil {
+		writeSubtaskError(w, err, "Cannot complete operation move todo")
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, todoId, model.TodoMoved, before, todo)
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+func todoIds(todos []model.Todo) []int {
+	var ids = make([]int, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.Id)
+	}
+	return ids
+}
+
+func writeSubtaskError(w http.ResponseWriter, err error, message string) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = "Todo not found"
+	case errors.Is(err, db.ErrAccessDenied):
+		errResponse.Code = http.StatusForbidden
+		errResponse.Message = "Access to parent todo denied"
+	case errors.Is(err, db.ErrInvalidOrder):
+		errResponse.Code = http.StatusBadRequest
+		errResponse.Message = err.Error()
+	case errors.Is(err, db.ErrCycle):
+		errResponse.Code = http.StatusConflict
+		errResponse.Message = err.Error()
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/utility"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type TagHandler struct {
+	Tags db.TagStore
+}
+
+// MyTagsHandler docs
+// @Summary Get my tags
+// @Tags tag
+// @ID my-tags-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Success  200 {array} model.Tag
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /tags [get]
+func (h *TagHandler) MyTagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	tags, err := h.Tags.GetTagsBy(r.Context(), userId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve tags",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// AddTagHandler docs
+// @Summary Create new tag
+// @Tags tag
+// @ID add-tag-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    body      body   request.TagForm     true  "form"
+// @Success  200 {object} model.Tag
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /tags [post]
+func (h *TagHandler) AddTagHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	tagForm, ok := decodeTagForm(w, r)
+	if !ok {
+		return
+	}
+	tag, err := h.Tags.CreateTagFor(r.Context(), userId, tagForm)
+	if err != nil {
+		writeTagError(w, err, "Cannot complete operation add tag")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// RenameTagHandler docs
+// @Summary Rename tag by id
+// @Tags tag
+// @ID rename-tag-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "tag id"
+// @Param    body      body   request.TagForm     true  "form"
+// @Success  200 {object} model.Tag
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /tags/{id} [put]
+func (h *TagHandler) RenameTagHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	tag, ok := h.authorizeTag(w, r)
+	if !ok {
+		return
+	}
+	tagForm, ok := decodeTagForm(w, r)
+	if !ok {
+		return
+	}
+	tag, err := h.Tags.UpdateTagBy(r.Context(), tag.Id, tagForm)
+	if err != nil {
+		writeTagError(w, err, "Cannot complete operation rename tag")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// RemoveTagHandler docs
+// @Summary Remove tag by id
+// @Description the tag is detached from all todos
+// @Tags tag
+// @ID remove-tag-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "tag id"
+// @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /tags/{id} [delete]
+func (h *TagHandler) RemoveTagHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	tag, ok := h.authorizeTag(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Tags.RemoveTagBy(r.Context(), tag.Id); err != nil {
+		writeTagError(w, err, "Cannot complete operation remove tag")
+		return
+	}
+	var response = model.Response{
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("Removed tag by %d", tag.Id),
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// TodoTagsHandler docs
+// @Summary Get my tags attached to todo
+// @Tags todo
+// @ID todo-tags-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Success  200 {array} model.Tag
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/tags [get]
+func (h *TodoHandler) TodoTagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	tags, err := h.Tags.GetTodoTags(r.Context(), userId, todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve tags",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// AttachTagHandler docs
+// @Summary Attach my tag to todo
+// @Description tags are private to their account, so viewers of a shared todo may tag it too
+// @Tags todo
+// @ID attach-tag-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    tagId      path   int     true  "tag id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {array} model.Tag
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/tags/{tagId} [put]
+func (h *TodoHandler) AttachTagHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeTodoTag(w, r, h.Tags.AttachTag, "Cannot complete operation attach tag")
+}
+
+// DetachTagHandler docs
+// @Summary Detach my tag from todo
+// @Tags todo
+// @ID detach-tag-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    tagId      path   int     true  "tag id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {array} model.Tag
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/tags/{tagId} [delete]
+func (h *TodoHandler) DetachTagHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeTodoTag(w, r, h.Tags.DetachTag, "Cannot complete operation detach tag")
+}
+
+// changeTodoTag applies attach or detach of the tag from the path and responds with the todo's tags.
+func (h *TodoHandler) changeTodoTag(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, todoId int, tagId int) error, message string) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok || !h.checkTodoPrecondition(w, r, todoId) {
+		return
+	}
+	tagId, err := strconv.Atoi(mux.Vars(r)["tagId"])
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve tag id",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if _, err := ownedTag(r, h.Tags, userId, tagId); err != nil {
+		writeTagError(w, err, "Cannot check access to tag")
+		return
+	}
+	if err := change(r.Context(), todoId, tagId); err != nil {
+		writeTagError(w, err, message)
+		return
+	}
+	tags, err := h.Tags.GetTodoTags(r.Context(), userId, todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve tags",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// authorizeTag resolves the tag from the path and makes sure it belongs to
+// the caller. On failure the response is written and false is returned.
+func (h *TagHandler) authorizeTag(w http.ResponseWriter, r *http.Request) (*model.Tag, bool) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		})
+		logger.Error("Cannot retrieve user id")
+		return nil, false
+	}
+	tagId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve tag id",
+		})
+		logger.Error("Cannot retrieve tag id", zap.Error(err))
+		return nil, false
+	}
+	tag, err := ownedTag(r, h.Tags, userId, tagId)
+	if err != nil {
+		writeTagError(w, err, "Cannot check access to tag")
+		return nil, false
+	}
+	return tag, true
+}
+
+func ownedTag(r *http.Request, tags db.TagStore, userId int, tagId int) (*model.Tag, error) {
+	tag, err := tags.GetTagBy(r.Context(), tagId)
+	if err != nil {
+		return nil, err
+	}
+	if tag.AccountId != userId {
+		return nil, db.ErrAccessDenied
+	}
+	return tag, nil
+}
+
+func writeTagError(w http.ResponseWriter, err error, message string) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = "Tag not found"
+	case errors.Is(err, db.ErrAccessDenied):
+		errResponse.Code = http.StatusForbidden
+		errResponse.Message = "Access to tag denied"
+	case errors.Is(err, db.ErrTagExists):
+		errResponse.Code = http.StatusConflict
+		errResponse.Message = err.Error()
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
+
+func decodeTagForm(w http.ResponseWriter, r *http.Request) (request.TagForm, bool) {
+	var tagForm request.TagForm
+	if err := json.NewDecoder(r.Body).Decode(&tagForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve tag form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return tagForm, false
+	}
+	if !tagForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Tag name must not be empty, longer than 64 characters or contain commas",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return tagForm, false
+	}
+	return tagForm, true
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// tagTodo attaches the tag of the account to the todo, creating the tag on first use.
+func (s *testServer) tagTodo(t *testing.T, accountId int, todoId int, name string) {
+	t.Helper()
+	tags, err := s.store.GetTagsBy(context.Background(), accountId)
+	if err != nil {
+		t.Fatalf("cannot read tags: %v", err)
+	}
+	var tagId int
+	for _, tag := range tags {
+		if tag.Name == name {
+			tagId = tag.Id
+		}
+	}
+	if tagId == 0 {
+		tag, err := s.store.CreateTagFor(context.Background(), accountId, request.TagForm{Name: name})
+		if err != nil {
+			t.Fatalf("cannot create tag %s: %v", name, err)
+		}
+		tagId = tag.Id
+	}
+	expectStatus(t, s.do(t, accountId, http.MethodPut, fmt.Sprintf("/todo/%d/tags/%d", todoId, tagId), ""), http.StatusOK)
+}
+
+func TestTagFilterOfMyTodos(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var tagged = []struct {
+		title string
+		tags  []string
+	}{
+		{"Report", []string{"work", "urgent"}},
+		{"Meeting", []string{"work"}},
+		{"Plumber", []string{"home", "urgent"}},
+		{"Novel", nil},
+	}
+	for _, todo := range tagged {
+		var id = s.addTodo(t, alice, fmt.Sprintf(`{"title":%q}`, todo.title)).Id
+		for _, name := range todo.tags {
+			s.tagTodo(t, alice, id, name)
+		}
+	}
+	var filters = []struct {
+		name   string
+		query  url.Values
+		titles []string
+	}{
+		{"single tag", url.Values{"tag": {"work"}}, []string{"Report", "Meeting"}},
+		{"all of", url.Values{"tag": {"work", "urgent"}}, []string{"Report"}},
+		{"any of", url.Values{"tag": {"work,home"}}, []string{"Report", "Meeting", "Plumber"}},
+		{"any of and all of", url.Values{"tag": {"work, home", "urgent"}}, []string{"Report", "Plumber"}},
+		{"case insensitive", url.Values{"tag": {"URGENT"}}, []string{"Report", "Plumber"}},
+		{"unknown tag", url.Values{"tag": {"garden"}}, []string{}},
+	}
+	for _, filter := range filters {
+		t.Run(filter.name, func(t *testing.T) {
+			titles, _ := s.pageTodos(t, alice, filter.query)
+			if fmt.Sprint(titles) != fmt.Sprint(filter.titles) {
+				t.Fatalf("expected %v, got %v", filter.titles, titles)
+			}
+		})
+	}
+}
+
+func TestEmptyTagFilterIsRejected(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	for _, query := range []string{"tag=", "tag=%2C%20"} {
+		t.Run(query, func(t *testing.T) {
+			expectStatus(t, s.do(t, alice, http.MethodGet, "/todo/my/todos?"+query, ""), http.StatusBadRequest)
+		})
+	}
+}
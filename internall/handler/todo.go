@@ -1,19 +1,56 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/IosifSuzuki/todo/internall/broadcast"
 	"github.com/IosifSuzuki/todo/internall/db"
 	"github.com/IosifSuzuki/todo/internall/logger"
 	"github.com/IosifSuzuki/todo/internall/model"
 	"github.com/IosifSuzuki/todo/internall/model/request"
+	"github.com/IosifSuzuki/todo/internall/storage"
 	"github.com/IosifSuzuki/todo/internall/utility"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 100
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 365
+)
+
+const invalidTodoFormMessage = "Todo title must not be empty, priority must be one of low, normal, high, urgent " +
+	"and recurring todo requires valid recurrence and due date"
+
+type TodoHandler struct {
+	Todos    db.TodoStore
+	Accounts db.AccountStore
+	Lists    db.ListStore
+	Tags     db.TagStore
+	Events   db.EventStore
+	// UndoWindow is how long after a change its author may still undo it.
+	UndoWindow  time.Duration
+	Attachments db.AttachmentStore
+	Blobs       storage.BlobStore
+	// MaxAttachmentSize is the largest file in bytes a single upload may hold.
+	MaxAttachmentSize int64
+	// AttachmentQuota is how many bytes of attachments an account may store in total.
+	AttachmentQuota int64
+	Comments        db.CommentStore
+	Notifications   db.NotificationStore
+	// Changes streams every recorded change to clients, it may be nil.
+	Changes *broadcast.Hub
+}
+
 // HomeHandler docs
 // @Summary Check connection to server through bearer token
 // @Tags todo
@@ -26,7 +63,7 @@ import (
 // @Failure  401 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/ping [get]
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	userId, ok := r.Context().Value(utility.UserIdKey).(int)
 	if !ok {
@@ -51,17 +88,30 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 
 // MyTodosHandler docs
 // @Summary Get my todos list server
+// @Description todos are returned page by page, pass next-cursor of the response as cursor to get the following page
 // @Tags todo
 // @ID my-todos-handler
 // @Accept   json
 // @Produce  json
 // @Security ApiKeyAuth
 // @param    Authorization header string true "Authorization"
-// @Success  200 {array} model.Todo
+// @Param    closed      query   bool     false  "filter by closed flag"
+// @Param    created-after      query   string     false  "created on or after, RFC 3339 timestamp or date"
+// @Param    created-before      query   string     false  "created before, RFC 3339 timestamp or date"
+// @Param    updated-after      query   string     false  "updated on or after, RFC 3339 timestamp or date"
+// @Param    updated-before      query   string     false  "updated before, RFC 3339 timestamp or date"
+// @Param    q      query   string     false  "case insensitive match in title or description"
+// @Param    tag      query   []string     false  "tag names, repeated parameters must all match, comma separated names are alternatives" collectionFormat(multi)
+// @Param    sort      query   string     false  "sort field, position is my manual order" Enums(position, id, title, created-on, updated-on) default(position)
+// @Param    order      query   string     false  "sort direction" Enums(asc, desc) default(asc)
+// @Param    limit      query   int     false  "page size" minimum(1) maximum(200) default(50)
+// @Param    cursor      query   string     false  "cursor of the page"
+// @Success  200 {object} model.TodoPage
+// @Failure  400 {object} model.ResponseError
 // @Failure  401 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/my/todos [get]
-func MyTodosHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) MyTodosHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	userId, ok := r.Context().Value(utility.UserIdKey).(int)
 	if !ok {
@@ -73,7 +123,17 @@ func MyTodosHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(err.Code)
 		return
 	}
-	todoModels, err := db.GetTodosBy(userId)
+	todoQuery, err := request.ParseTodoQuery(r.URL.Query())
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+		logger.Error("Cannot parse todo query", zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	todoPage, err := h.Todos.GetTodosPage(r.Context(), userId, todoQuery)
 	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
@@ -84,7 +144,7 @@ func MyTodosHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(todoModels); err != nil {
+	if err := json.NewEncoder(w).Encode(todoPage); err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
 			Message: "Error occurred during encoding",
@@ -95,8 +155,153 @@ func MyTodosHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// OverdueTodosHandler docs
+// @Summary Get my open todos which are past their due date
+// @Tags todo
+// @ID overdue-todos-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Success  200 {array} model.Todo
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/my/overdue [get]
+func (h *TodoHandler) OverdueTodosHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	h.writeDueTodos(w, r, nil, time.Now())
+}
+
+// UpcomingTodosHandler docs
+// @Summary Get my open todos which are due within the next days
+// @Tags todo
+// @ID upcoming-todos-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    days      query   int     false  "number of days to look ahead" minimum(1) maximum(365) default(7)
+// @Success  200 {array} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/my/upcoming [get]
+func (h *TodoHandler) UpcomingTodosHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	var days = defaultUpcomingDays
+	if value := r.URL.Query().Get("days"); len(value) != 0 {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days < 1 || days > maxUpcomingDays {
+			errResponse := model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Days must be between 1 and %d", maxUpcomingDays),
+			}
+			logger.Error(errResponse.Message, zap.String("days", value))
+			writeResponseError(w, errResponse)
+			return
+		}
+	}
+	var now = time.Now()
+	h.writeDueTodos(w, r, &now, now.AddDate(0, 0, days))
+}
+
+func (h *TodoHandler) writeDueTodos(w http.ResponseWriter, r *http.Request, from *time.Time, to time.Time) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	todos, err := h.Todos.GetOpenTodosDue(r.Context(), userId, from, to)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo models",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todos); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// SearchTodosHandler docs
+// @Summary Full-text search in titles and descriptions of my todos
+// @Description results are ranked by relevance, matched words are wrapped into <mark> tags in snippets
+// @Tags todo
+// @ID search-todos-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    q      query   string     true  "search text"
+// @Param    limit      query   int     false  "maximum number of results" minimum(1) maximum(100) default(20)
+// @Success  200 {array} model.TodoSearchResult
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/search [get]
+func (h *TodoHandler) SearchTodosHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		errResponse := model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var text = strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(text) == 0 {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Search text must not be empty",
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var limit = defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); len(value) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSearchLimit {
+			errResponse := model.ResponseError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit),
+			}
+			logger.Error(errResponse.Message, zap.String("limit", value))
+			writeResponseError(w, errResponse)
+			return
+		}
+	}
+	results, err := h.Todos.SearchTodos(r.Context(), userId, text, limit)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete search of todo items",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
 // RemoveTodoHandler docs
 // @Summary Remove todo by id
+// @Description the todo and its subtasks are moved into the trash, they can be restored until the trash retention passes
 // @Tags todo
 // @ID remove-todo-handler
 // @Accept   json
@@ -104,24 +309,39 @@ func MyTodosHandler(w http.ResponseWriter, r *http.Request) {
 // @Security ApiKeyAuth
 // @param    Authorization header string true "Authorization"
 // @Param    id      path   int     true  "todo id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
 // @Success  200 {object} model.Response
+// @Failure  400 {object} model.ResponseError
 // @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/remove/{id} [delete]
-func RemoveTodoHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) RemoveTodoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	todoId, err := strconv.Atoi(vars["id"])
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleOwner)
+	if !ok {
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
 	if err != nil {
 		errResponse := model.ResponseError{
-			Code:    http.StatusBadRequest,
-			Message: "Cannot retrieve todo id",
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
 		}
 		logger.Error(errResponse.Message, zap.Error(err))
-		w.WriteHeader(errResponse.Code)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, todo) {
+		return
+	}
+	err = h.Todos.RemoveTodoBy(r.Context(), todoId, expectedVersion(r, todo))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, todo)
 		return
 	}
-	err = db.RemoveTodoBy(todoId)
 	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
@@ -131,6 +351,7 @@ func RemoveTodoHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(errResponse.Code)
 		return
 	}
+	h.recordTodoEvent(r, todoId, model.TodoDeleted, todo, nil)
 	var response = model.Response{
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("Removed todo by %d", todoId),
@@ -160,7 +381,7 @@ func RemoveTodoHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure  401 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/add [post]
-func AddTodoHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) AddTodoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	userId, ok := r.Context().Value(utility.UserIdKey).(int)
 	if !ok {
@@ -182,7 +403,19 @@ func AddTodoHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(errResponse.Code)
 		return
 	}
-	todo, err := db.CreteTodoFor(userId, todoForm)
+	if !todoForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: invalidTodoFormMessage,
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !h.checkTodoList(w, r, userId, todoForm.ListId, nil) {
+		return
+	}
+	todo, err := h.Todos.CreteTodoFor(r.Context(), userId, todoForm)
 	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
@@ -192,6 +425,7 @@ func AddTodoHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(errResponse.Code)
 		return
 	}
+	h.recordTodoEvent(r, todo.Id, model.TodoCreated, nil, todo)
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(todo); err != nil {
 		errResponse := model.ResponseError{
@@ -213,23 +447,30 @@ func AddTodoHandler(w http.ResponseWriter, r *http.Request) {
 // @param    Authorization header string true "Authorization"
 // @Param    id      path   int     true  "todo id"
 // @Success  200 {object} model.Todo
+// @Header   200 {string} ETag "version of the todo, send it back as If-Match to detect concurrent changes"
+// @Failure  400 {object} model.ResponseError
 // @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/{id} [get]
-func GetTodoHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) GetTodoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	todoId, err := strconv.Atoi(vars["id"])
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	todoModel, err := h.Todos.GetTodoBy(r.Context(), todoId)
 	if err != nil {
 		errResponse := model.ResponseError{
-			Code:    http.StatusBadRequest,
-			Message: "Cannot retrieve todo id",
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
 		}
 		logger.Error(errResponse.Message, zap.Error(err))
 		w.WriteHeader(errResponse.Code)
 		return
 	}
-	todoModel, err := db.GetTodoBy(todoId)
+	writeTodoETag(w, todoModel)
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(todoModel); err != nil {
 		errResponse := model.ResponseError{
@@ -244,6 +485,8 @@ func GetTodoHandler(w http.ResponseWriter, r *http.Request) {
 
 // ToggleTodoHandler docs
 // @Summary Toggle todo by id
+// @Deprecated
+// @Description a retried toggle flips the todo back, use close and reopen instead. Closing an occurrence of a recurring todo generates the next occurrence of its series
 // @Tags todo
 // @ID toggle-todo-handler
 // @Accept   json
@@ -251,24 +494,40 @@ func GetTodoHandler(w http.ResponseWriter, r *http.Request) {
 // @Security ApiKeyAuth
 // @param    Authorization header string true "Authorization"
 // @Param    id      path   int     true  "todo id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
 // @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
 // @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
 // @Failure  500 {object} model.ResponseError
 // @Router   /todo/toggle/{id} [put]
-func ToggleTodoHandler(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) ToggleTodoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	todoId, err := strconv.Atoi(vars["id"])
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	before, err := h.Todos.GetTodoBy(r.Context(), todoId)
 	if err != nil {
 		errResponse := model.ResponseError{
-			Code:    http.StatusBadRequest,
-			Message: "Cannot retrieve todo id",
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
 		}
 		logger.Error(errResponse.Message, zap.Error(err))
-		w.WriteHeader(errResponse.Code)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, before) {
+		return
+	}
+	todo, next, err := h.Todos.ToggleTodoFor(r.Context(), todoId, userId, expectedVersion(r, before))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, before)
 		return
 	}
-	if err := db.ToggleTodoFor(todoId); err != nil {
+	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
 			Message: "Cannot toggle todo id",
@@ -277,23 +536,243 @@ func ToggleTodoHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(errResponse.Code)
 		return
 	}
-	todo, err := db.GetTodoBy(todoId)
+	h.recordTodoEvent(r, todoId, model.TodoToggled, before, todo)
+	h.recordNextOccurrence(r, todo, next)
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Error occurred during encoding",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		w.WriteHeader(errResponse.Code)
+	}
+}
+
+// UpdateTodoHandler docs
+// @Summary Replace todo by id
+// @Description replaces title, description, list, due date, priority, reminder and recurrence of the todo, fields left out of the form are cleared and priority falls back to normal
+// @Tags todo
+// @ID update-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.TodoForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id} [put]
+func (h *TodoHandler) UpdateTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	var todoForm request.TodoForm
+	if err := json.NewDecoder(r.Body).Decode(&todoForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve todo form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
 	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
 			Message: "Cannot retrieve todo model from db",
 		}
 		logger.Error(errResponse.Message, zap.Error(err))
-		w.WriteHeader(errResponse.Code)
+		writeResponseError(w, errResponse)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(todo); err != nil {
+	if !checkIfMatch(w, r, todo) {
+		return
+	}
+	h.updateTodo(w, r, userId, todo, todoForm)
+}
+
+// PatchTodoHandler docs
+// @Summary Partially update todo by id with JSON Merge Patch
+// @Tags todo
+// @ID patch-todo-handler
+// @Accept   json
+// @Accept   application/merge-patch+json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.TodoForm     true  "merge patch"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id} [patch]
+func (h *TodoHandler) PatchTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot read merge patch from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
 		errResponse := model.ResponseError{
 			Code:    http.StatusInternalServerError,
-			Message: "Error occurred during encoding",
+			Message: "Cannot retrieve todo model from db",
 		}
 		logger.Error(errResponse.Message, zap.Error(err))
-		w.WriteHeader(errResponse.Code)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var todoForm = request.NewTodoForm(todo)
+	if err := todoForm.ApplyMergePatch(patch); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot apply merge patch to todo",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, todo) {
+		return
+	}
+	h.updateTodo(w, r, userId, todo, todoForm)
+}
+
+func (h *TodoHandler) updateTodo(w http.ResponseWriter, r *http.Request, userId int, current *model.Todo, todoForm request.TodoForm) {
+	if !todoForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: invalidTodoFormMessage,
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !h.checkTodoList(w, r, userId, todoForm.ListId, current.ListId) {
+		return
+	}
+	todo, err := h.Todos.UpdateTodoBy(r.Context(), current.Id, todoForm, expectedVersion(r, current))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, current)
+		return
+	}
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation update todo item",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Todo %d not found", current.Id),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, current.Id, model.TodoUpdated, current, todo)
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// checkTodoList makes sure a todo is only moved into a list owned by the caller.
+// Keeping the current list is always allowed, so editors of shared todos can
+// update them without owning the list.
+func (h *TodoHandler) checkTodoList(w http.ResponseWriter, r *http.Request, userId int, listId *int, currentListId *int) bool {
+	if listId == nil || (currentListId != nil && *listId == *currentListId) {
+		return true
+	}
+	if _, err := ownedList(r, h.Lists, userId, *listId); err != nil {
+		writeListAccessError(w, err, *listId)
+		return false
+	}
+	return true
+}
+
+// authorizeTodo resolves the todo id from the path and makes sure the caller
+// is linked to it through account_item with at least the required role.
+// On failure the response is written and false is returned.
+func (h *TodoHandler) authorizeTodo(w http.ResponseWriter, r *http.Request, required model.Role) (int, int, bool) {
+	return h.authorizeTodoWith(w, r, required, h.Todos.GetTodoRole)
+}
+
+// authorizeTodoWith is authorizeTodo with a custom role lookup, e.g. for todos in the trash.
+func (h *TodoHandler) authorizeTodoWith(w http.ResponseWriter, r *http.Request, required model.Role,
+	todoRole func(ctx context.Context, userId int, todoId int) (model.Role, error)) (int, int, bool) {
+	userId, ok := r.Context().Value(utility.UserIdKey).(int)
+	if !ok {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusUnauthorized,
+			Message: "Cannot retrieve user id",
+		})
+		logger.Error("Cannot retrieve user id")
+		return 0, 0, false
+	}
+	todoId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeResponseError(w, model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve todo id",
+		})
+		logger.Error("Cannot retrieve todo id", zap.Error(err))
+		return 0, 0, false
+	}
+	role, err := todoRole(r.Context(), userId, todoId)
+	if err == nil && !role.Allows(required) {
+		err = db.ErrAccessDenied
+	}
+	if err != nil {
+		var errResponse model.ResponseError
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			errResponse = model.ResponseError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Todo %d not found", todoId),
+			}
+		case errors.Is(err, db.ErrAccessDenied):
+			errResponse = model.ResponseError{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("Access to todo %d denied", todoId),
+			}
+		default:
+			errResponse = model.ResponseError{
+				Code:    http.StatusInternalServerError,
+				Message: "Cannot check access to todo",
+			}
+		}
+		logger.Error(errResponse.Message, zap.Int("user id", userId), zap.Error(err))
+		writeResponseError(w, errResponse)
+		return 0, 0, false
 	}
+	return userId, todoId, true
 }
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// pageTodos walks the my-todos listing from the first page along the next cursors
+// and returns the titles and ids in the order they were served.
+func (s *testServer) pageTodos(t *testing.T, accountId int, query url.Values) ([]string, []int) {
+	t.Helper()
+	var titles = make([]string, 0)
+	var ids = make([]int, 0)
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("cursor does not advance, served %v", ids)
+		}
+		var w = s.do(t, accountId, http.MethodGet, "/todo/my/todos?"+query.Encode(), "")
+		expectStatus(t, w, http.StatusOK)
+		var page model.TodoPage
+		decodeResponse(t, w, &page)
+		for _, todo := range page.Todos {
+			titles = append(titles, todo.Title)
+			ids = append(ids, todo.Id)
+		}
+		if len(page.NextCursor) == 0 {
+			return titles, ids
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+func TestTodoPagesFollowCursors(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	for _, title := range []string{"Eggs", "Bread", "Apples", "Dates", "Cheese"} {
+		s.addTodo(t, alice, fmt.Sprintf(`{"title":%q}`, title))
+	}
+	var requests = []struct {
+		name   string
+		query  url.Values
+		titles []string
+	}{
+		{"position", url.Values{"limit": {"2"}}, []string{"Eggs", "Bread", "Apples", "Dates", "Cheese"}},
+		{"title", url.Values{"limit": {"2"}, "sort": {"title"}}, []string{"Apples", "Bread", "Cheese", "Dates", "Eggs"}},
+		{"title desc", url.Values{"limit": {"3"}, "sort": {"title"}, "order": {"desc"}}, []string{"Eggs", "Dates", "Cheese", "Bread", "Apples"}},
+		{"created-on", url.Values{"limit": {"1"}, "sort": {"created-on"}}, []string{"Eggs", "Bread", "Apples", "Dates", "Cheese"}},
+	}
+	for _, request := range requests {
+		t.Run(request.name, func(t *testing.T) {
+			titles, _ := s.pageTodos(t, alice, request.query)
+			if fmt.Sprint(titles) != fmt.Sprint(request.titles) {
+				t.Fatalf("expected %v, got %v", request.titles, titles)
+			}
+		})
+	}
+}
+
+func TestTodoPagesBreakTiesById(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var expected = make([]int, 0)
+	for i := 0; i < 5; i++ {
+		expected = append(expected, s.addTodo(t, alice, `{"title":"Same"}`).Id)
+	}
+	_, ids := s.pageTodos(t, alice, url.Values{"limit": {"2"}, "sort": {"title"}})
+	if fmt.Sprint(ids) != fmt.Sprint(expected) {
+		t.Fatalf("expected every todo once in id order %v, got %v", expected, ids)
+	}
+	_, ids = s.pageTodos(t, alice, url.Values{"limit": {"2"}, "sort": {"title"}, "order": {"desc"}})
+	for i, id := range ids {
+		if id != expected[len(expected)-1-i] || len(ids) != len(expected) {
+			t.Fatalf("expected every todo once in reverse id order, got %v", ids)
+		}
+	}
+}
+
+func TestTodoCursorOfAnotherSortIsRejected(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	for _, title := range []string{"Eggs", "Bread", "Apples"} {
+		s.addTodo(t, alice, fmt.Sprintf(`{"title":%q}`, title))
+	}
+	var w = s.do(t, alice, http.MethodGet, "/todo/my/todos?sort=title&limit=1", "")
+	expectStatus(t, w, http.StatusOK)
+	var page model.TodoPage
+	decodeResponse(t, w, &page)
+	if len(page.NextCursor) == 0 {
+		t.Fatal("expected a next cursor")
+	}
+	for _, query := range []string{"sort=id", "sort=title&order=desc", "limit=1"} {
+		t.Run(query, func(t *testing.T) {
+			var path = fmt.Sprintf("/todo/my/todos?%s&cursor=%s", query, page.NextCursor)
+			expectStatus(t, s.do(t, alice, http.MethodGet, path, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestMalformedTodoCursorIsRejected(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	s.addTodo(t, alice, `{"title":"Eggs"}`)
+	var encode = func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	var requests = []struct {
+		name  string
+		query string
+	}{
+		{"not base64", "cursor=%21%21%21"},
+		{"not json", "cursor=" + encode("not json")},
+		{"bad time", "sort=created-on&cursor=" + encode(`{"s":"created-on","v":"yesterday","i":1}`)},
+		{"bad position", "cursor=" + encode(`{"s":"position","v":"first","i":1}`)},
+		{"bad id", "sort=id&cursor=" + encode(`{"s":"id","i":"one"}`)},
+	}
+	for _, request := range requests {
+		t.Run(request.name, func(t *testing.T) {
+			expectStatus(t, s.do(t, alice, http.MethodGet, "/todo/my/todos?"+request.query, ""), http.StatusBadRequest)
+		})
+	}
+}
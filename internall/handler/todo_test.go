@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/http"
+	"testing"
+)
+
+func TestTodoOfAnotherAccountIsForbidden(t *testing.T) {
+	var s = newTestServer(t)
+	var alice, bob = s.signUp(t, "alice"), s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Pay rent"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	var requests = []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"get", http.MethodGet, path, ""},
+		{"toggle", http.MethodPut, fmt.Sprintf("/todo/toggle/%d", todo.Id), ""},
+		{"update", http.MethodPut, path, `{"title":"Stolen"}`},
+		{"patch", http.MethodPatch, path, `{"title":"Stolen"}`},
+		{"remove", http.MethodDelete, fmt.Sprintf("/todo/remove/%d", todo.Id), ""},
+	}
+	for _, request := range requests {
+		t.Run(request.name, func(t *testing.T) {
+			expectStatus(t, s.do(t, bob, request.method, request.path, request.body), http.StatusForbidden)
+		})
+	}
+	var unchanged = s.getTodo(t, alice, todo.Id)
+	if unchanged.Title != todo.Title || unchanged.Closed || unchanged.Version != todo.Version {
+		t.Fatalf("todo was changed by another account: %+v", unchanged)
+	}
+}
+
+func TestMissingTodoIsNotFound(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var requests = []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"get", http.MethodGet, "/todo/42", ""},
+		{"toggle", http.MethodPut, "/todo/toggle/42", ""},
+		{"update", http.MethodPut, "/todo/42", `{"title":"Missing"}`},
+		{"remove", http.MethodDelete, "/todo/remove/42", ""},
+	}
+	for _, request := range requests {
+		t.Run(request.name, func(t *testing.T) {
+			expectStatus(t, s.do(t, alice, request.method, request.path, request.body), http.StatusNotFound)
+		})
+	}
+}
+
+func TestViewerCannotChangeSharedTodo(t *testing.T) {
+	var s = newTestServer(t)
+	var alice, bob = s.signUp(t, "alice"), s.signUp(t, "bob")
+	var todo = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	var path = fmt.Sprintf("/todo/%d", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, path+"/share", `{"user-name":"bob","role":"viewer"}`), http.StatusOK)
+	expectStatus(t, s.do(t, bob, http.MethodGet, path, ""), http.StatusOK)
+	expectStatus(t, s.do(t, bob, http.MethodPut, fmt.Sprintf("/todo/toggle/%d", todo.Id), ""), http.StatusForbidden)
+	expectStatus(t, s.do(t, bob, http.MethodPut, path, `{"title":"Stolen"}`), http.StatusForbidden)
+	expectStatus(t, s.do(t, bob, http.MethodDelete, fmt.Sprintf("/todo/remove/%d", todo.Id), ""), http.StatusForbidden)
+}
+
+// trashingStore moves the todo into the trash right after the handler read it.
+type trashingStore struct {
+	*db.MemoryStore
+}
+
+func (s *trashingStore) GetTodoBy(ctx context.Context, todoId int) (*model.Todo, error) {
+	todo, err := s.MemoryStore.GetTodoBy(ctx, todoId)
+	if err != nil {
+		return todo, err
+	}
+	return todo, s.MemoryStore.RemoveTodoBy(ctx, todoId, nil)
+}
+
+func TestUpdateOfTrashedTodoIsNotFound(t *testing.T) {
+	var s = newTestServer(t)
+	s.todos.Todos = &trashingStore{MemoryStore: s.store}
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Pay rent"}`)
+	expectStatus(t, s.do(t, alice, http.MethodPut, fmt.Sprintf("/todo/%d", todo.Id), `{"title":"Changed"}`), http.StatusNotFound)
+	trashed, err := s.store.GetTrashedTodos(context.Background(), alice)
+	if err != nil {
+		t.Fatalf("cannot read trash: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].Title != todo.Title || trashed[0].Version != todo.Version+1 {
+		t.Fatalf("expected the trashed todo unchanged, got %+v", trashed)
+	}
+}
+
+func TestSearchSnippetsAreEscaped(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	s.addTodo(t, alice, `{"title":"<script>alert(1)</script> report","description":"Send the <b>report</b> & slides"}`)
+	var w = s.do(t, alice, http.MethodGet, "/todo/search?q=report", "")
+	expectStatus(t, w, http.StatusOK)
+	var results []model.TodoSearchResult
+	decodeResponse(t, w, &results)
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %+v", results)
+	}
+	var expectedTitle = "&lt;script&gt;alert(1)&lt;/script&gt; <mark>report</mark>"
+	if results[0].TitleSnippet != expectedTitle {
+		t.Fatalf("expected title snippet %q, got %q", expectedTitle, results[0].TitleSnippet)
+	}
+	var expectedDescription = "Send the &lt;b&gt;<mark>report</mark>&lt;/b&gt; &amp; slides"
+	if results[0].DescriptionSnippet != expectedDescription {
+		t.Fatalf("expected description snippet %q, got %q", expectedDescription, results[0].DescriptionSnippet)
+	}
+}
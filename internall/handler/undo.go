@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// undoableActions are the history actions undo can revert from their before snapshot.
+var undoableActions = []model.TodoAction{
+	model.TodoToggled,
+	model.TodoClosed,
+	model.TodoReopened,
+	model.TodoTransitioned,
+	model.TodoUpdated,
+	model.TodoMoved,
+	model.TodoDeleted,
+}
+
+// UndoTodoHandler docs
+// @Summary Undo my latest change of todo
+// @Description reverts the latest toggle, close, reopen, transition, update, move or removal of the todo when the caller made it within the undo window, repeated calls walk further back. Closing a recurring todo which generated its next occurrence cannot be undone
+// @Tags todo
+// @ID undo-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/undo [post]
+func (h *TodoHandler) UndoTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodoWith(w, r, model.RoleEditor, h.anyTodoRole)
+	if !ok {
+		return
+	}
+	event, recent, err := h.Events.GetLastTodoEvent(r.Context(), todoId, undoableActions, h.UndoWindow)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo history",
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			errResponse = model.ResponseError{
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("Nothing to undo for todo %d", todoId),
+			}
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var conflict string
+	switch {
+	case event.ActorId == nil || *event.ActorId != userId:
+		conflict = fmt.Sprintf("Latest change of todo %d was made by another account", todoId)
+	case !recent:
+		conflict = fmt.Sprintf("Latest change of todo %d is older than the undo window", todoId)
+	}
+	if len(conflict) != 0 {
+		errResponse := model.ResponseError{
+			Code:    http.StatusConflict,
+			Message: conflict,
+		}
+		logger.Error(errResponse.Message, zap.Int("event id", event.Id))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if event.Action == model.TodoDeleted {
+		if _, _, ok := h.authorizeTodoWith(w, r, model.RoleOwner, h.anyTodoRole); !ok {
+			return
+		}
+	}
+	var before model.Todo
+	if err := json.Unmarshal(event.Before, &before); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot read todo snapshot of the change",
+		}
+		logger.Error(errResponse.Message, zap.Int("event id", event.Id), zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	var current *model.Todo
+	if event.Action != model.TodoDeleted {
+		if current, err = h.Todos.GetTodoBy(r.Context(), todoId); err != nil {
+			errResponse := model.ResponseError{
+				Code:    http.StatusInternalServerError,
+				Message: "Cannot retrieve todo model from db",
+			}
+			logger.Error(errResponse.Message, zap.Error(err))
+			writeResponseError(w, errResponse)
+			return
+		}
+		if !checkIfMatch(w, r, current) {
+			return
+		}
+	}
+	if spawnedOccurrence(&before, current) {
+		errResponse := model.ResponseError{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("Closing todo %d generated its next occurrence, reopen the todo instead", todoId),
+		}
+		logger.Error(errResponse.Message, zap.Int("event id", event.Id))
+		writeResponseError(w, errResponse)
+		return
+	}
+	// The revert is guarded by the version of the current todo, so of two concurrent
+	// undos only one gets past it and the event is marked once the revert applied.
+	err = h.revertTodoEvent(r.Context(), userId, event.Action, current, &before)
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, current)
+		return
+	}
+	if err != nil {
+		writeUndoError(w, err)
+		return
+	}
+	if err := h.Events.MarkTodoEventUndone(r.Context(), event.Id); err != nil {
+		writeUndoError(w, err)
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	h.recordTodoEvent(r, todoId, model.TodoUndone, current, todo)
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// revertTodoEvent brings the todo back to the before snapshot of a change. The revert
+// only applies while the todo is still at the version of current.
+func (h *TodoHandler) revertTodoEvent(ctx context.Context, userId int, action model.TodoAction, current *model.Todo, before *model.Todo) error {
+	switch action {
+	case model.TodoToggled, model.TodoClosed, model.TodoReopened:
+		if current.Closed == before.Closed {
+			return nil
+		}
+		_, _, _, err := h.Todos.SetTodoClosed(ctx, before.Id, userId, before.Closed, &current.Version)
+		return err
+	case model.TodoTransitioned:
+		if current.Status == before.Status {
+			return nil
+		}
+		_, _, _, err := h.Todos.TransitionTodo(ctx, before.Id, userId, before.Status, &current.Version)
+		return err
+	case model.TodoUpdated:
+		var todoForm = request.NewTodoForm(before)
+		if todoForm.ListId != nil {
+			if _, err := h.Lists.GetListBy(ctx, *todoForm.ListId); errors.Is(err, db.ErrNotFound) {
+				todoForm.ListId = nil
+			}
+		}
+		_, err := h.Todos.UpdateTodoBy(ctx, before.Id, todoForm, &current.Version)
+		return err
+	case model.TodoMoved:
+		return h.Todos.SetTodoParent(ctx, before.Id, before.ParentId, &current.Version)
+	case model.TodoDeleted:
+		return h.Todos.RestoreTodoBy(ctx, before.Id)
+	}
+	return fmt.Errorf("cannot undo %s", action)
+}
+
+// spawnedOccurrence tells whether the change closed a recurring todo whose series goes on,
+// so it generated the next occurrence. Undo would leave that occurrence behind.
+func spawnedOccurrence(before *model.Todo, current *model.Todo) bool {
+	if current == nil || before.Closed || !current.Closed || current.Recurrence == nil || current.DueAt == nil {
+		return false
+	}
+	_, ok := current.Recurrence.Anchored(*current.DueAt).Next(*current.DueAt, current.Occurrence)
+	return ok
+}
+
+// anyTodoRole looks up the role of the account on the todo whether it is in the trash or not.
+func (h *TodoHandler) anyTodoRole(ctx context.Context, userId int, todoId int) (model.Role, error) {
+	role, err := h.Todos.GetTodoRole(ctx, userId, todoId)
+	if errors.Is(err, db.ErrNotFound) {
+		return h.Todos.GetTrashedTodoRole(ctx, userId, todoId)
+	}
+	return role, err
+}
+
+func writeUndoError(w http.ResponseWriter, err error) {
+	var errResponse = model.ResponseError{
+		Code:    http.StatusInternalServerError,
+		Message: "Cannot complete operation undo",
+	}
+	switch {
+	case errors.Is(err, db.ErrAlreadyUndone), errors.Is(err, db.ErrCycle):
+		errResponse.Code = http.StatusConflict
+		errResponse.Message = err.Error()
+	case errors.Is(err, db.ErrInvalidTransition), errors.Is(err, db.ErrUnknownState):
+		errResponse.Code = http.StatusConflict
+		errResponse.Message = "Workflow of the todo does not allow going back to its previous state"
+	case errors.Is(err, db.ErrNotFound):
+		errResponse.Code = http.StatusNotFound
+		errResponse.Message = "Todo not found"
+	}
+	logger.Error(errResponse.Message, zap.Error(err))
+	writeResponseError(w, errResponse)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/http"
+	"testing"
+)
+
+func TestUndoRevertsLatestTransition(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Write report"}`)
+	expectStatus(t, s.do(t, alice, http.MethodPut, fmt.Sprintf("/todo/toggle/%d", todo.Id), ""), http.StatusOK)
+	var body = fmt.Sprintf(`{"status":%q}`, model.StatusInProgress)
+	expectStatus(t, s.do(t, alice, http.MethodPost, fmt.Sprintf("/todo/%d/transition", todo.Id), body), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPost, fmt.Sprintf("/todo/%d/undo", todo.Id), ""), http.StatusOK)
+	var current = s.getTodo(t, alice, todo.Id)
+	if current.Status != model.StatusDone || !current.Closed {
+		t.Fatalf("expected the transition to be undone, got status %s closed %v", current.Status, current.Closed)
+	}
+}
+
+func TestFailedUndoCanBeRetried(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var parent = s.addTodo(t, alice, `{"title":"Plan trip"}`)
+	var todo = s.addTodo(t, alice, `{"title":"Book hotel"}`)
+	var setParent = func(todoId int, parentId string) {
+		t.Helper()
+		var path = fmt.Sprintf("/todo/%d/parent", todoId)
+		expectStatus(t, s.do(t, alice, http.MethodPut, path, `{"parent-id":`+parentId+`}`), http.StatusOK)
+	}
+	setParent(todo.Id, fmt.Sprint(parent.Id))
+	setParent(todo.Id, "null")
+	setParent(parent.Id, fmt.Sprint(todo.Id))
+	var undo = fmt.Sprintf("/todo/%d/undo", todo.Id)
+	expectStatus(t, s.do(t, alice, http.MethodPost, undo, ""), http.StatusConflict)
+	setParent(parent.Id, "null")
+	expectStatus(t, s.do(t, alice, http.MethodPost, undo, ""), http.StatusOK)
+	if current := s.getTodo(t, alice, todo.Id); current.ParentId == nil || *current.ParentId != parent.Id {
+		t.Fatalf("expected todo back under %d, got %v", parent.Id, current.ParentId)
+	}
+}
+
+func TestUndoAfterConcurrentChangeFailsPrecondition(t *testing.T) {
+	var s = newTestServer(t)
+	var store = &racingStore{MemoryStore: s.store}
+	s.todos.Todos = store
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Buy milk"}`)
+	expectStatus(t, s.do(t, alice, http.MethodPut, fmt.Sprintf("/todo/toggle/%d", todo.Id), ""), http.StatusOK)
+	var undo = fmt.Sprintf("/todo/%d/undo", todo.Id)
+	store.race = true
+	expectStatus(t, s.do(t, alice, http.MethodPost, undo, ""), http.StatusPreconditionFailed)
+	if current := s.getTodo(t, alice, todo.Id); !current.Closed {
+		t.Fatal("expected the todo to stay closed after the failed undo")
+	}
+	expectStatus(t, s.do(t, alice, http.MethodPost, undo, ""), http.StatusOK)
+	if current := s.getTodo(t, alice, todo.Id); current.Closed {
+		t.Fatal("expected the retried undo to reopen the todo")
+	}
+}
+
+func TestUndoOfRecurringCloseIsRejected(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, monthlyTodo)
+	expectStatus(t, s.do(t, alice, http.MethodPost, fmt.Sprintf("/todo/%d/close", todo.Id), ""), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPost, fmt.Sprintf("/todo/%d/undo", todo.Id), ""), http.StatusConflict)
+	var series = s.occurrences(t, alice, todo.Id)
+	if len(series) != 2 || !series[1].Closed || series[2].Closed {
+		t.Fatalf("expected the closed todo and its open next occurrence, got %+v", series)
+	}
+}
+
+func TestUndoOfLastOccurrenceCloseReopens(t *testing.T) {
+	var s = newTestServer(t)
+	var alice = s.signUp(t, "alice")
+	var todo = s.addTodo(t, alice, `{"title":"Pay rent","due-at":"2027-01-31T09:00:00Z","recurrence":{"frequency":"monthly","count":1}}`)
+	expectStatus(t, s.do(t, alice, http.MethodPut, fmt.Sprintf("/todo/toggle/%d", todo.Id), ""), http.StatusOK)
+	expectStatus(t, s.do(t, alice, http.MethodPost, fmt.Sprintf("/todo/%d/undo", todo.Id), ""), http.StatusOK)
+	var series = s.occurrences(t, alice, todo.Id)
+	if len(series) != 1 || series[1].Closed {
+		t.Fatalf("expected the only occurrence to be reopened, got %+v", series)
+	}
+}
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/db"
+	"github.com/IosifSuzuki/todo/internall/logger"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"github.com/IosifSuzuki/todo/internall/model/request"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ListWorkflowHandler docs
+// @Summary Get workflow of list
+// @Description lists without their own workflow use the default one: backlog, in-progress, blocked and done
+// @Tags list
+// @ID list-workflow-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Success  200 {object} model.Workflow
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id}/workflow [get]
+func (h *ListHandler) ListWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	workflow, err := h.Lists.GetWorkflow(r.Context(), &list.Id)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve workflow",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(workflow); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// SetListWorkflowHandler docs
+// @Summary Replace workflow of list
+// @Description the first state is where new and reopened todos start and must not be terminal, todos in terminal states are closed. Todos in a removed state move to the first terminal state when closed and to the first state otherwise
+// @Tags list
+// @ID set-list-workflow-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "list id"
+// @Param    body      body   request.WorkflowForm     true  "form"
+// @Success  200 {object} model.Workflow
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /lists/{id}/workflow [put]
+func (h *ListHandler) SetListWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, list, ok := h.authorizeList(w, r)
+	if !ok {
+		return
+	}
+	var workflowForm request.WorkflowForm
+	if err := json.NewDecoder(r.Body).Decode(&workflowForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve workflow form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	workflowForm.Normalize()
+	if !workflowForm.IsValidated() {
+		errResponse := model.ResponseError{
+			Code: http.StatusBadRequest,
+			Message: fmt.Sprintf("Workflow requires 2 to %d uniquely named states of at most %d characters, "+
+				"a non-terminal first state, a terminal state and transitions between different states",
+				request.MaxWorkflowStates, request.MaxWorkflowStateName),
+		}
+		logger.Error(errResponse.Message)
+		writeResponseError(w, errResponse)
+		return
+	}
+	var workflow = workflowForm.Workflow()
+	if err := h.Lists.SetWorkflow(r.Context(), list.Id, workflow); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation replace workflow",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(workflow); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// TodoWorkflowHandler docs
+// @Summary Get workflow which applies to todo
+// @Tags todo
+// @ID todo-workflow-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Success  200 {object} model.Workflow
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/workflow [get]
+func (h *TodoHandler) TodoWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, todoId, ok := h.authorizeTodo(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	todo, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	workflow, err := h.Lists.GetWorkflow(r.Context(), todo.ListId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve workflow",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(workflow); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
+
+// TransitionTodoHandler docs
+// @Summary Move todo into another state of its workflow
+// @Description the workflow of the todo's list must allow the transition from the current status, moving into the current status changes nothing. Closed follows the terminal flag of the new state
+// @Tags todo
+// @ID transition-todo-handler
+// @Accept   json
+// @Produce  json
+// @Security ApiKeyAuth
+// @param    Authorization header string true "Authorization"
+// @Param    id      path   int     true  "todo id"
+// @Param    body      body   request.TransitionForm     true  "form"
+// @Param    If-Match      header   string     false  "ETag of the todo the change is based on"
+// @Success  200 {object} model.Todo
+// @Failure  400 {object} model.ResponseError
+// @Failure  401 {object} model.ResponseError
+// @Failure  403 {object} model.ResponseError
+// @Failure  404 {object} model.ResponseError
+// @Failure  409 {object} model.ResponseError
+// @Failure  412 {object} model.ResponseError
+// @Failure  500 {object} model.ResponseError
+// @Router   /todo/{id}/transition [post]
+func (h *TodoHandler) TransitionTodoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	userId, todoId, ok := h.authorizeTodo(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	var transitionForm request.TransitionForm
+	if err := json.NewDecoder(r.Body).Decode(&transitionForm); err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusBadRequest,
+			Message: "Cannot retrieve transition form from request",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	transitionForm.Normalize()
+	before, err := h.Todos.GetTodoBy(r.Context(), todoId)
+	if err != nil {
+		errResponse := model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot retrieve todo model from db",
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if !checkIfMatch(w, r, before) {
+		return
+	}
+	todo, next, changed, err := h.Todos.TransitionTodo(r.Context(), todoId, userId, transitionForm.Status, expectedVersion(r, before))
+	if errors.Is(err, db.ErrVersionMismatch) {
+		writePreconditionFailed(w, before)
+		return
+	}
+	if err != nil {
+		var errResponse = model.ResponseError{
+			Code:    http.StatusInternalServerError,
+			Message: "Cannot complete operation transition todo",
+		}
+		switch {
+		case errors.Is(err, db.ErrUnknownState):
+			errResponse.Code = http.StatusBadRequest
+			errResponse.Message = fmt.Sprintf("Status %q is not a state of the workflow", transitionForm.Status)
+		case errors.Is(err, db.ErrInvalidTransition):
+			errResponse.Code = http.StatusConflict
+			errResponse.Message = fmt.Sprintf("Workflow does not allow todo %d to move from %s to %s",
+				todoId, before.Status, transitionForm.Status)
+		case errors.Is(err, db.ErrNotFound):
+			errResponse.Code = http.StatusNotFound
+			errResponse.Message = fmt.Sprintf("Todo %d not found", todoId)
+		}
+		logger.Error(errResponse.Message, zap.Error(err))
+		writeResponseError(w, errResponse)
+		return
+	}
+	if changed {
+		h.recordTodoEvent(r, todoId, model.TodoTransitioned, before, todo)
+		h.recordNextOccurrence(r, todo, next)
+	}
+	writeTodoETag(w, todo)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		logger.Error("Error occurred during encoding", zap.Error(err))
+	}
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Attachment describes a file uploaded to a todo, the content itself is kept in the blob storage under StorageKey.
+type Attachment struct {
+	Id          int       `json:"id"`
+	TodoId      int       `json:"todo-id"`
+	AccountId   int       `json:"account-id"`
+	FileName    string    `json:"file-name"`
+	ContentType string    `json:"content-type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedOn   time.Time `json:"created-on"`
+}
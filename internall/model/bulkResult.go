@@ -0,0 +1,17 @@
+package model
+
+// BulkResult reports the outcome of one operation of a bulk request. Code follows
+// HTTP status codes, operations which were not applied because another one
+// failed report 424 Failed Dependency.
+type BulkResult struct {
+	Index   int    `json:"index"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+	Todo    *Todo  `json:"todo,omitempty"`
+}
+
+// BulkResponse is applied when all operations succeeded, otherwise none of them took effect.
+type BulkResponse struct {
+	Applied bool         `json:"applied"`
+	Results []BulkResult `json:"results"`
+}
@@ -0,0 +1,7 @@
+package model
+
+type Collaborator struct {
+	AccountId int    `json:"account-id"`
+	UserName  string `json:"user-name"`
+	Role      Role   `json:"role"`
+}
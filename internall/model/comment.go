@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Comment is an entry of the discussion of a todo, the body is markdown and
+// rendered by clients. AuthorId is nil once the author's account is gone.
+type Comment struct {
+	Id         int       `json:"id"`
+	TodoId     int       `json:"todo-id"`
+	AuthorId   *int      `json:"author-id"`
+	AuthorName string    `json:"author-name"`
+	Body       string    `json:"body"`
+	CreatedOn  time.Time `json:"created-on"`
+	UpdatedOn  time.Time `json:"updated-on"`
+}
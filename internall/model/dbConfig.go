@@ -3,10 +3,11 @@ package model
 import "fmt"
 
 type DBConfig struct {
-	UserName string
-	Password string
-	DBName   string
-	DBHost   string
+	UserName       string
+	Password       string
+	DBName         string
+	DBHost         string
+	MaxConnections int32
 }
 
 const testMode = true
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type List struct {
+	Id        int       `json:"id"`
+	AccountId int       `json:"account-id"`
+	Name      string    `json:"name"`
+	Colour    string    `json:"colour"`
+	Archived  bool      `json:"archived"`
+	CreatedOn time.Time `json:"created-on"`
+	UpdatedOn time.Time `json:"updated-on"`
+}
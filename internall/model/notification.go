@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type NotificationKind string
+
+const (
+	NotificationMentioned NotificationKind = "mentioned"
+	NotificationShared    NotificationKind = "shared"
+	NotificationReminder  NotificationKind = "reminder"
+	NotificationAssigned  NotificationKind = "assigned"
+)
+
+// Notification tells an account about something another account did. Data holds
+// a snapshot of what the notification is about: the comment for mentioned, the
+// collaborator for shared, a TodoReminder for reminder and the todo for assigned.
+// Reminders have no actor.
+type Notification struct {
+	Id        int              `json:"id"`
+	AccountId int              `json:"account-id"`
+	Kind      NotificationKind `json:"kind"`
+	TodoId    *int             `json:"todo-id"`
+	ActorId   *int             `json:"actor-id"`
+	ActorName string           `json:"actor-name"`
+	Data      json.RawMessage  `json:"data" swaggertype:"object"`
+	ReadAt    *time.Time       `json:"read-at"`
+	CreatedOn time.Time        `json:"created-on"`
+}
+
+type TodoReminder struct {
+	Title    string     `json:"title"`
+	DueAt    *time.Time `json:"due-at"`
+	RemindAt time.Time  `json:"remind-at"`
+}
+
+type UnreadNotifications struct {
+	Count int `json:"count"`
+}
@@ -0,0 +1,135 @@
+package model
+
+import "time"
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is a subset of the iCalendar RRULE: FREQ, INTERVAL, BYDAY, BYMONTHDAY, UNTIL and COUNT.
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	Interval  int       `json:"interval,omitempty"`
+	ByWeekday []string  `json:"by-weekday,omitempty"`
+	// ByMonthDay is the day of month of a monthly series, months without it use their last day.
+	ByMonthDay int        `json:"by-month-day,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	Count      *int       `json:"count,omitempty"`
+}
+
+func (r *Recurrence) IsValid() bool {
+	switch r.Frequency {
+	case FrequencyDaily:
+		if len(r.ByWeekday) != 0 || r.ByMonthDay != 0 {
+			return false
+		}
+	case FrequencyMonthly:
+		if len(r.ByWeekday) != 0 || r.ByMonthDay < 0 || r.ByMonthDay > 31 {
+			return false
+		}
+	case FrequencyWeekly:
+		if r.ByMonthDay != 0 {
+			return false
+		}
+		for _, weekday := range r.ByWeekday {
+			if _, ok := weekdays[weekday]; !ok {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	if r.Interval < 0 || (r.Count != nil && *r.Count < 1) {
+		return false
+	}
+	return r.Until == nil || r.Count == nil
+}
+
+// Anchored returns a copy of the recurrence which pins a monthly series to the day
+// of month of due, so clamping a short month does not move the later occurrences.
+func (r *Recurrence) Anchored(due time.Time) *Recurrence {
+	var anchored = *r
+	if anchored.Frequency == FrequencyMonthly && anchored.ByMonthDay == 0 {
+		anchored.ByMonthDay = due.Day()
+	}
+	return &anchored
+}
+
+// Next returns the due date of the occurrence which follows the one due at current.
+// occurrence is the 1-based position of the current occurrence in the series.
+// false is returned when the series is over.
+func (r *Recurrence) Next(current time.Time, occurrence int) (time.Time, bool) {
+	if r.Count != nil && occurrence >= *r.Count {
+		return time.Time{}, false
+	}
+	var interval = r.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	var next time.Time
+	switch r.Frequency {
+	case FrequencyDaily:
+		next = current.AddDate(0, 0, interval)
+	case FrequencyWeekly:
+		next = r.nextWeekly(current, interval)
+	case FrequencyMonthly:
+		var day = r.ByMonthDay
+		if day == 0 {
+			day = current.Day()
+		}
+		next = addMonthsClamped(current, interval, day)
+	}
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r *Recurrence) nextWeekly(current time.Time, interval int) time.Time {
+	if len(r.ByWeekday) == 0 {
+		return current.AddDate(0, 0, 7*interval)
+	}
+	var allowed = make(map[time.Weekday]bool, len(r.ByWeekday))
+	for _, weekday := range r.ByWeekday {
+		allowed[weekdays[weekday]] = true
+	}
+	var weekStart = startOfWeek(current)
+	for day := 1; ; day++ {
+		var candidate = current.AddDate(0, 0, day)
+		var weeks = int(startOfWeek(candidate).Sub(weekStart).Hours()/24+0.5) / 7
+		if allowed[candidate.Weekday()] && weeks%interval == 0 {
+			return candidate
+		}
+	}
+}
+
+// startOfWeek returns midnight of the monday of the week t belongs to.
+func startOfWeek(t time.Time) time.Time {
+	var offset = (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// addMonthsClamped adds months and moves to the day of month, a day missing in
+// the target month is clamped to its last day.
+func addMonthsClamped(t time.Time, months int, day int) time.Time {
+	var firstOfTarget = time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	var lastDay = firstOfTarget.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfTarget.AddDate(0, 0, day-1)
+}
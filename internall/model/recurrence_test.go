@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonthlyRecurrenceKeepsMonthEnd(t *testing.T) {
+	var due = time.Date(2027, time.January, 31, 9, 0, 0, 0, time.UTC)
+	var recurrence = (&Recurrence{Frequency: FrequencyMonthly}).Anchored(due)
+	var expected = []time.Time{
+		time.Date(2027, time.February, 28, 9, 0, 0, 0, time.UTC),
+		time.Date(2027, time.March, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2027, time.April, 30, 9, 0, 0, 0, time.UTC),
+		time.Date(2027, time.May, 31, 9, 0, 0, 0, time.UTC),
+	}
+	for occurrence, want := range expected {
+		next, ok := recurrence.Next(due, occurrence+1)
+		if !ok || !next.Equal(want) {
+			t.Fatalf("occurrence %d: expected %v, got %v (%t)", occurrence+2, want, next, ok)
+		}
+		due = next
+	}
+}
+
+func TestRecurrenceStopsAfterCount(t *testing.T) {
+	var count = 2
+	var recurrence = &Recurrence{Frequency: FrequencyDaily, Count: &count}
+	var due = time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if _, ok := recurrence.Next(due, 1); !ok {
+		t.Fatal("expected a second occurrence")
+	}
+	if _, ok := recurrence.Next(due, 2); ok {
+		t.Fatal("expected the series to end after two occurrences")
+	}
+}
+
+func TestMonthDayOnlyForMonthlyRecurrence(t *testing.T) {
+	if (&Recurrence{Frequency: FrequencyWeekly, ByMonthDay: 3}).IsValid() {
+		t.Fatal("weekly recurrence must not accept by-month-day")
+	}
+	if (&Recurrence{Frequency: FrequencyMonthly, ByMonthDay: 32}).IsValid() {
+		t.Fatal("by-month-day must be a day of month")
+	}
+	if !(&Recurrence{Frequency: FrequencyMonthly, ByMonthDay: 31}).IsValid() {
+		t.Fatal("monthly recurrence must accept by-month-day 31")
+	}
+}
@@ -0,0 +1,6 @@
+package request
+
+// AssignForm names the collaborator a todo is assigned to, a null user name unassigns the todo.
+type AssignForm struct {
+	UserName *string `json:"user-name"`
+}
@@ -0,0 +1,55 @@
+package request
+
+import "github.com/IosifSuzuki/todo/internall/model"
+
+const MaxBulkOperations = 100
+
+type BulkOp string
+
+const (
+	BulkCreate BulkOp = "create"
+	BulkClose  BulkOp = "close"
+	BulkReopen BulkOp = "reopen"
+	BulkDelete BulkOp = "delete"
+	BulkMove   BulkOp = "move"
+	BulkTag    BulkOp = "tag"
+)
+
+// BulkOperation is one step of a bulk request. Todo is used by create, TodoId
+// by every other op, ListId by move (null detaches from the list) and TagId by tag.
+// Version, when set, makes the operation fail unless the todo is still at it.
+type BulkOperation struct {
+	Op      BulkOp    `json:"op"`
+	TodoId  int       `json:"todo-id,omitempty"`
+	Todo    *TodoForm `json:"todo,omitempty"`
+	ListId  *int      `json:"list-id,omitempty"`
+	TagId   int       `json:"tag-id,omitempty"`
+	Version *int      `json:"version,omitempty"`
+}
+
+type BulkForm struct {
+	Operations []BulkOperation `json:"operations"`
+}
+
+func (b *BulkOperation) IsValidated() bool {
+	switch b.Op {
+	case BulkCreate:
+		return b.Todo != nil && b.Todo.IsValidated()
+	case BulkClose, BulkReopen, BulkDelete, BulkMove:
+		return b.TodoId > 0
+	case BulkTag:
+		return b.TodoId > 0 && b.TagId > 0
+	}
+	return false
+}
+
+// RequiredRole is the role on the todo the operation needs, the same as the single todo endpoints.
+func (b *BulkOperation) RequiredRole() model.Role {
+	switch b.Op {
+	case BulkDelete:
+		return model.RoleOwner
+	case BulkTag:
+		return model.RoleViewer
+	}
+	return model.RoleEditor
+}
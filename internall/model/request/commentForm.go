@@ -0,0 +1,17 @@
+package request
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 10000
+
+type CommentForm struct {
+	Body string `json:"body"`
+}
+
+func (c *CommentForm) IsValidated() bool {
+	var body = strings.TrimSpace(c.Body)
+	return len(body) != 0 && utf8.RuneCountInString(body) <= maxCommentLength
+}
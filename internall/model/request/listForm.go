@@ -0,0 +1,21 @@
+package request
+
+import (
+	"regexp"
+	"strings"
+)
+
+var colourPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type ListForm struct {
+	Name     string `json:"name"`
+	Colour   string `json:"colour"`
+	Archived bool   `json:"archived"`
+}
+
+func (l *ListForm) IsValidated() bool {
+	if len(strings.TrimSpace(l.Name)) == 0 {
+		return false
+	}
+	return len(l.Colour) == 0 || colourPattern.MatchString(l.Colour)
+}
@@ -0,0 +1,20 @@
+package request
+
+// MoveForm places a todo right before or right after another todo of the
+// caller's manual order, exactly one of the anchors must be set.
+type MoveForm struct {
+	Before *int `json:"before"`
+	After  *int `json:"after"`
+}
+
+func (m *MoveForm) IsValidated() bool {
+	return (m.Before == nil) != (m.After == nil)
+}
+
+// Anchor returns the todo id to move next to and whether the todo goes after it.
+func (m *MoveForm) Anchor() (int, bool) {
+	if m.After != nil {
+		return *m.After, true
+	}
+	return *m.Before, false
+}
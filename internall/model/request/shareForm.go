@@ -0,0 +1,12 @@
+package request
+
+import "github.com/IosifSuzuki/todo/internall/model"
+
+type ShareForm struct {
+	UserName string     `json:"user-name"`
+	Role     model.Role `json:"role"`
+}
+
+func (s *ShareForm) IsValidated() bool {
+	return len(s.UserName) != 0 && s.Role.IsValid()
+}
@@ -0,0 +1,9 @@
+package request
+
+type ParentForm struct {
+	ParentId *int `json:"parent-id"`
+}
+
+type SubtaskOrderForm struct {
+	SubtaskIds []int `json:"subtask-ids"`
+}
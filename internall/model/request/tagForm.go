@@ -0,0 +1,18 @@
+package request
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const maxTagNameLength = 64
+
+type TagForm struct {
+	Name string `json:"name"`
+}
+
+// IsValidated rejects commas in names since they separate alternatives in the tag filter of the my-todos listing.
+func (t *TagForm) IsValidated() bool {
+	var name = strings.TrimSpace(t.Name)
+	return len(name) != 0 && utf8.RuneCountInString(name) <= maxTagNameLength && !strings.Contains(name, ",")
+}
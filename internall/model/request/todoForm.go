@@ -1,6 +1,109 @@
 package request
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"strings"
+	"time"
+)
+
 type TodoForm struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	ListId      *int              `json:"list-id"`
+	DueAt       *time.Time        `json:"due-at"`
+	Priority    model.Priority    `json:"priority"`
+	RemindAt    *time.Time        `json:"remind-at"`
+	Recurrence  *model.Recurrence `json:"recurrence"`
+}
+
+// NewTodoForm returns the form which reproduces the editable fields of the todo.
+func NewTodoForm(todo *model.Todo) TodoForm {
+	return TodoForm{
+		Title:       todo.Title,
+		Description: todo.Description,
+		ListId:      todo.ListId,
+		DueAt:       todo.DueAt,
+		Priority:    todo.Priority,
+		RemindAt:    todo.RemindAt,
+		Recurrence:  todo.Recurrence,
+	}
+}
+
+func (t *TodoForm) IsValidated() bool {
+	if len(strings.TrimSpace(t.Title)) == 0 {
+		return false
+	}
+	if len(t.Priority) != 0 && !t.Priority.IsValid() {
+		return false
+	}
+	return t.Recurrence == nil || (t.Recurrence.IsValid() && t.DueAt != nil)
+}
+
+// EffectivePriority falls back to normal priority when the form leaves it out.
+func (t *TodoForm) EffectivePriority() model.Priority {
+	if len(t.Priority) == 0 {
+		return model.PriorityNormal
+	}
+	return t.Priority
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) document on top of the form.
+// Nested objects such as the recurrence are merged member by member, members set
+// to null are reset to their zero value.
+func (t *TodoForm) ApplyMergePatch(patch []byte) error {
+	var patchDocument interface{}
+	if err := decodeJSON(patch, &patchDocument); err != nil {
+		return err
+	}
+	if _, ok := patchDocument.(map[string]interface{}); !ok {
+		return errors.New("merge patch must be a JSON object")
+	}
+	original, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := decodeJSON(original, &document); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergePatch(document, patchDocument))
+	if err != nil {
+		return err
+	}
+	var patched TodoForm
+	if err := json.Unmarshal(merged, &patched); err != nil {
+		return err
+	}
+	*t = patched
+	return nil
+}
+
+// mergePatch applies the patch to the target as described by RFC 7396.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMembers, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMembers, ok := target.(map[string]interface{})
+	if !ok {
+		targetMembers = make(map[string]interface{})
+	}
+	for key, value := range patchMembers {
+		if value == nil {
+			delete(targetMembers, key)
+		} else {
+			targetMembers[key] = mergePatch(targetMembers[key], value)
+		}
+	}
+	return targetMembers
+}
+
+// decodeJSON decodes numbers as json.Number, so they survive the round trip unchanged.
+func decodeJSON(data []byte, v interface{}) error {
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
 }
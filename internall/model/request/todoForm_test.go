@@ -0,0 +1,50 @@
+package request
+
+import (
+	"github.com/IosifSuzuki/todo/internall/model"
+	"testing"
+	"time"
+)
+
+func TestMergePatchMergesRecurrence(t *testing.T) {
+	var due = time.Date(2027, time.March, 1, 9, 0, 0, 0, time.UTC)
+	var count = 5
+	var form = TodoForm{
+		Title:      "Water plants",
+		DueAt:      &due,
+		Recurrence: &model.Recurrence{Frequency: model.FrequencyWeekly, ByWeekday: []string{"MO"}, Count: &count},
+	}
+	if err := form.ApplyMergePatch([]byte(`{"recurrence":{"interval":2,"count":null}}`)); err != nil {
+		t.Fatal(err)
+	}
+	var recurrence = form.Recurrence
+	if recurrence == nil || recurrence.Frequency != model.FrequencyWeekly || recurrence.Interval != 2 {
+		t.Fatalf("expected weekly recurrence with interval 2, got %+v", recurrence)
+	}
+	if len(recurrence.ByWeekday) != 1 || recurrence.ByWeekday[0] != "MO" {
+		t.Fatalf("expected by-weekday to be kept, got %v", recurrence.ByWeekday)
+	}
+	if recurrence.Count != nil {
+		t.Fatalf("expected count to be removed, got %d", *recurrence.Count)
+	}
+	if form.Title != "Water plants" || form.DueAt == nil || !form.DueAt.Equal(due) {
+		t.Fatalf("expected other members to be kept, got %+v", form)
+	}
+}
+
+func TestMergePatchRemovesRecurrence(t *testing.T) {
+	var form = TodoForm{Title: "Water plants", Recurrence: &model.Recurrence{Frequency: model.FrequencyDaily}}
+	if err := form.ApplyMergePatch([]byte(`{"recurrence":null}`)); err != nil {
+		t.Fatal(err)
+	}
+	if form.Recurrence != nil {
+		t.Fatalf("expected recurrence to be removed, got %+v", form.Recurrence)
+	}
+}
+
+func TestMergePatchMustBeObject(t *testing.T) {
+	var form = TodoForm{Title: "Water plants"}
+	if err := form.ApplyMergePatch([]byte(`["title"]`)); err == nil {
+		t.Fatal("expected an array patch to be rejected")
+	}
+}
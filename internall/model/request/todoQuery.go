@@ -0,0 +1,209 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IosifSuzuki/todo/internall/model"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTodoPageLimit = 50
+	MaxTodoPageLimit     = 200
+)
+
+type TodoSortField string
+
+const (
+	SortById        TodoSortField = "id"
+	SortByTitle     TodoSortField = "title"
+	SortByCreatedOn TodoSortField = "created-on"
+	SortByUpdatedOn TodoSortField = "updated-on"
+	SortByPosition  TodoSortField = "position"
+)
+
+func (t TodoSortField) IsValid() bool {
+	switch t {
+	case SortById, SortByTitle, SortByCreatedOn, SortByUpdatedOn, SortByPosition:
+		return true
+	}
+	return false
+}
+
+// TodoCursor points right after the last todo of a page. It carries the sort
+// settings it was issued for, so it cannot be replayed against another order.
+type TodoCursor struct {
+	SortField  TodoSortField `json:"s"`
+	Descending bool          `json:"d"`
+	Value      string        `json:"v"`
+	Id         int           `json:"i"`
+}
+
+type TodoQuery struct {
+	Closed        *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Text          string
+	Tags          [][]string
+	SortField     TodoSortField
+	Descending    bool
+	Limit         int
+	Cursor        *TodoCursor
+}
+
+// ParseTodoQuery reads filters, sort order and pagination of the my-todos listing from query parameters.
+func ParseTodoQuery(values url.Values) (TodoQuery, error) {
+	var todoQuery = TodoQuery{
+		Text:      strings.TrimSpace(values.Get("q")),
+		SortField: SortByPosition,
+		Limit:     DefaultTodoPageLimit,
+	}
+	var err error
+	if value := values.Get("closed"); len(value) != 0 {
+		closed, err := strconv.ParseBool(value)
+		if err != nil {
+			return todoQuery, fmt.Errorf("closed: %w", err)
+		}
+		todoQuery.Closed = &closed
+	}
+	if todoQuery.CreatedAfter, err = parseTime(values, "created-after"); err != nil {
+		return todoQuery, err
+	}
+	if todoQuery.CreatedBefore, err = parseTime(values, "created-before"); err != nil {
+		return todoQuery, err
+	}
+	if todoQuery.UpdatedAfter, err = parseTime(values, "updated-after"); err != nil {
+		return todoQuery, err
+	}
+	if todoQuery.UpdatedBefore, err = parseTime(values, "updated-before"); err != nil {
+		return todoQuery, err
+	}
+	if todoQuery.Tags, err = parseTags(values["tag"]); err != nil {
+		return todoQuery, err
+	}
+	if value := values.Get("sort"); len(value) != 0 {
+		todoQuery.SortField = TodoSortField(value)
+		if !todoQuery.SortField.IsValid() {
+			return todoQuery, fmt.Errorf("sort: unknown field %q", value)
+		}
+	}
+	switch value := values.Get("order"); value {
+	case "", "asc":
+	case "desc":
+		todoQuery.Descending = true
+	default:
+		return todoQuery, fmt.Errorf("order: unknown direction %q", value)
+	}
+	if value := values.Get("limit"); len(value) != 0 {
+		if todoQuery.Limit, err = strconv.Atoi(value); err != nil {
+			return todoQuery, fmt.Errorf("limit: %w", err)
+		}
+		if todoQuery.Limit < 1 || todoQuery.Limit > MaxTodoPageLimit {
+			return todoQuery, fmt.Errorf("limit: must be between 1 and %d", MaxTodoPageLimit)
+		}
+	}
+	if value := values.Get("cursor"); len(value) != 0 {
+		if todoQuery.Cursor, err = decodeTodoCursor(value); err != nil {
+			return todoQuery, err
+		}
+		if todoQuery.Cursor.SortField != todoQuery.SortField || todoQuery.Cursor.Descending != todoQuery.Descending {
+			return todoQuery, errors.New("cursor: issued for another sort order")
+		}
+	}
+	return todoQuery, nil
+}
+
+// NextCursor builds the cursor of the page which follows the given todo.
+func (t *TodoQuery) NextCursor(last model.Todo) string {
+	var cursor = TodoCursor{
+		SortField:  t.SortField,
+		Descending: t.Descending,
+		Id:         last.Id,
+	}
+	switch t.SortField {
+	case SortByTitle:
+		cursor.Value = last.Title
+	case SortByCreatedOn:
+		cursor.Value = last.CreatedOn.Format(time.RFC3339Nano)
+	case SortByUpdatedOn:
+		cursor.Value = last.UpdatedOn.Format(time.RFC3339Nano)
+	case SortByPosition:
+		cursor.Value = strconv.FormatInt(last.Position, 10)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// TimeValue returns the cursor value of a timestamp sort field.
+func (t *TodoCursor) TimeValue() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, t.Value)
+}
+
+// PositionValue returns the cursor value of the position sort field.
+func (t *TodoCursor) PositionValue() (int64, error) {
+	return strconv.ParseInt(t.Value, 10, 64)
+}
+
+func decodeTodoCursor(value string) (*TodoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("cursor: %w", err)
+	}
+	var cursor TodoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("cursor: %w", err)
+	}
+	if cursor.SortField == SortByCreatedOn || cursor.SortField == SortByUpdatedOn {
+		if _, err := cursor.TimeValue(); err != nil {
+			return nil, fmt.Errorf("cursor: %w", err)
+		}
+	}
+	if cursor.SortField == SortByPosition {
+		if _, err := cursor.PositionValue(); err != nil {
+			return nil, fmt.Errorf("cursor: %w", err)
+		}
+	}
+	return &cursor, nil
+}
+
+// parseTags reads the tag filter: repeated tag parameters must all match while
+// comma separated names inside one parameter are alternatives, so
+// tag=work,home&tag=urgent selects urgent todos tagged either work or home.
+// Names are lower cased since tags are matched case insensitively.
+func parseTags(values []string) ([][]string, error) {
+	var tags = make([][]string, 0, len(values))
+	for _, value := range values {
+		var alternatives = make([]string, 0)
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); len(name) != 0 {
+				alternatives = append(alternatives, strings.ToLower(name))
+			}
+		}
+		if len(alternatives) == 0 {
+			return nil, errors.New("tag: expected comma separated tag names")
+		}
+		tags = append(tags, alternatives)
+	}
+	return tags, nil
+}
+
+func parseTime(values url.Values, key string) (*time.Time, error) {
+	var value = values.Get(key)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			parsed = parsed.UTC()
+			return &parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: expected RFC 3339 timestamp or date", key)
+}
@@ -0,0 +1,76 @@
+package request
+
+import (
+	"github.com/IosifSuzuki/todo/internall/model"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxWorkflowStates    = 20
+	MaxWorkflowStateName = 32
+)
+
+type WorkflowForm struct {
+	States      []model.WorkflowState      `json:"states"`
+	Transitions []model.WorkflowTransition `json:"transitions"`
+}
+
+type TransitionForm struct {
+	Status string `json:"status"`
+}
+
+// Normalize trims and lower cases state names, they are matched case insensitively.
+func (w *WorkflowForm) Normalize() {
+	for i := range w.States {
+		w.States[i].Name = normalizeStatus(w.States[i].Name)
+	}
+	for i := range w.Transitions {
+		w.Transitions[i].From = normalizeStatus(w.Transitions[i].From)
+		w.Transitions[i].To = normalizeStatus(w.Transitions[i].To)
+	}
+}
+
+// IsValidated requires between 2 and MaxWorkflowStates uniquely named states, a
+// non-terminal first state, at least one terminal state, and transitions between
+// two different known states.
+func (w *WorkflowForm) IsValidated() bool {
+	if len(w.States) < 2 || len(w.States) > MaxWorkflowStates || w.States[0].Terminal {
+		return false
+	}
+	var names = make(map[string]bool)
+	var terminal = false
+	for _, state := range w.States {
+		if len(state.Name) == 0 || utf8.RuneCountInString(state.Name) > MaxWorkflowStateName || names[state.Name] {
+			return false
+		}
+		names[state.Name] = true
+		terminal = terminal || state.Terminal
+	}
+	if !terminal {
+		return false
+	}
+	var transitions = make(map[model.WorkflowTransition]bool)
+	for _, transition := range w.Transitions {
+		if !names[transition.From] || !names[transition.To] || transition.From == transition.To || transitions[transition] {
+			return false
+		}
+		transitions[transition] = true
+	}
+	return true
+}
+
+func (w *WorkflowForm) Workflow() model.Workflow {
+	return model.Workflow{
+		States:      w.States,
+		Transitions: append(make([]model.WorkflowTransition, 0, len(w.Transitions)), w.Transitions...),
+	}
+}
+
+func (t *TransitionForm) Normalize() {
+	t.Status = normalizeStatus(t.Status)
+}
+
+func normalizeStatus(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
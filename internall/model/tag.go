@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type Tag struct {
+	Id        int       `json:"id"`
+	AccountId int       `json:"account-id"`
+	Name      string    `json:"name"`
+	CreatedOn time.Time `json:"created-on"`
+}
@@ -3,10 +3,32 @@ package model
 import "time"
 
 type Todo struct {
-	Id          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatedOn   time.Time `json:"created-on"`
-	UpdatedOn   time.Time `json:"updated-on"`
-	Closed      bool      `json:"closed"`
+	Id          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	CreatedOn   time.Time  `json:"created-on"`
+	UpdatedOn   time.Time  `json:"updated-on"`
+	Closed      bool       `json:"closed"`
+	ClosedAt    *time.Time `json:"closed-at"`
+	ClosedBy    *int       `json:"closed-by"`
+	// Status is the state of the todo in the workflow of its list, closed is true for terminal states.
+	Status     string      `json:"status"`
+	ListId     *int        `json:"list-id"`
+	DueAt      *time.Time  `json:"due-at"`
+	Priority   Priority    `json:"priority"`
+	RemindAt   *time.Time  `json:"remind-at"`
+	Recurrence *Recurrence `json:"recurrence"`
+	SeriesId   *int        `json:"series-id"`
+	Occurrence int         `json:"occurrence"`
+	ParentId   *int        `json:"parent-id"`
+	// AssigneeId is the collaborator responsible for the todo, nil when nobody is assigned.
+	AssigneeId *int       `json:"assignee-id"`
+	DeletedAt  *time.Time `json:"deleted-at"`
+	// Version grows with every change of the todo, it is sent as the ETag of the todo.
+	Version int `json:"version"`
+	// Position is the place of the todo in the manual order of the caller,
+	// it is only filled in listings of the caller's todos.
+	Position int64 `json:"position,omitempty"`
+	// Progress is the percentage of closed direct subtasks, nil when the todo has none.
+	Progress *int `json:"progress"`
 }
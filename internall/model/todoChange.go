@@ -0,0 +1,16 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TodoChange is a history event as streamed to clients while it happens.
+type TodoChange struct {
+	TodoId    int             `json:"todo-id"`
+	ActorId   int             `json:"actor-id"`
+	Action    TodoAction      `json:"action"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	CreatedOn time.Time       `json:"created-on"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TodoAction string
+
+const (
+	TodoCreated      TodoAction = "created"
+	TodoUpdated      TodoAction = "updated"
+	TodoToggled      TodoAction = "toggled"
+	TodoClosed       TodoAction = "closed"
+	TodoReopened     TodoAction = "reopened"
+	TodoTransitioned TodoAction = "transitioned"
+	TodoDeleted      TodoAction = "deleted"
+	TodoRestored     TodoAction = "restored"
+	TodoShared       TodoAction = "shared"
+	TodoRevoked      TodoAction = "revoked"
+	TodoAssigned     TodoAction = "assigned"
+	TodoMoved        TodoAction = "moved"
+	TodoReordered    TodoAction = "reordered"
+	TodoAttached     TodoAction = "attached"
+	TodoDetached     TodoAction = "detached"
+	TodoUndone       TodoAction = "undone"
+)
+
+// TodoEvent is an entry of the todo history. Before and after hold snapshots of
+// the todo, of the collaborator for shared and revoked, of the subtask ids for reordered
+// and of the attachment for attached and detached.
+// Undone marks events reverted through undo, the revert itself is recorded as an undone event.
+type TodoEvent struct {
+	Id        int             `json:"id"`
+	TodoId    int             `json:"todo-id"`
+	ActorId   *int            `json:"actor-id"`
+	ActorName string          `json:"actor-name"`
+	Action    TodoAction      `json:"action"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	Undone    bool            `json:"undone"`
+	CreatedOn time.Time       `json:"created-on"`
+}
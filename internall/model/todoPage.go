@@ -0,0 +1,6 @@
+package model
+
+type TodoPage struct {
+	Todos      []Todo `json:"todos"`
+	NextCursor string `json:"next-cursor,omitempty"`
+}
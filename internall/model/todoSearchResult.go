@@ -0,0 +1,8 @@
+package model
+
+type TodoSearchResult struct {
+	Todo               Todo    `json:"todo"`
+	Rank               float32 `json:"rank"`
+	TitleSnippet       string  `json:"title-snippet"`
+	DescriptionSnippet string  `json:"description-snippet"`
+}
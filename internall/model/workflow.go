@@ -0,0 +1,93 @@
+package model
+
+// Workflow lists the states a todo of a list goes through. The first state is
+// where new and reopened todos start, todos in a terminal state count as closed.
+type Workflow struct {
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+type WorkflowState struct {
+	Name     string `json:"name"`
+	Terminal bool   `json:"terminal"`
+}
+
+type WorkflowTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+const (
+	StatusBacklog    = "backlog"
+	StatusInProgress = "in-progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+)
+
+// DefaultWorkflow applies to todos outside of lists and to lists without their own workflow.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		States: []WorkflowState{
+			{Name: StatusBacklog},
+			{Name: StatusInProgress},
+			{Name: StatusBlocked},
+			{Name: StatusDone, Terminal: true},
+		},
+		Transitions: []WorkflowTransition{
+			{From: StatusBacklog, To: StatusInProgress},
+			{From: StatusBacklog, To: StatusDone},
+			{From: StatusInProgress, To: StatusBacklog},
+			{From: StatusInProgress, To: StatusBlocked},
+			{From: StatusInProgress, To: StatusDone},
+			{From: StatusBlocked, To: StatusBacklog},
+			{From: StatusBlocked, To: StatusInProgress},
+			{From: StatusDone, To: StatusBacklog},
+			{From: StatusDone, To: StatusInProgress},
+		},
+	}
+}
+
+func (w Workflow) State(name string) (WorkflowState, bool) {
+	for _, state := range w.States {
+		if state.Name == name {
+			return state, true
+		}
+	}
+	return WorkflowState{}, false
+}
+
+func (w Workflow) Allows(from string, to string) bool {
+	for _, transition := range w.Transitions {
+		if transition.From == from && transition.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Initial is the state of new and reopened todos.
+func (w Workflow) Initial() string {
+	return w.States[0].Name
+}
+
+// FirstTerminal is the state todos are put in when they are closed without a transition.
+func (w Workflow) FirstTerminal() string {
+	for _, state := range w.States {
+		if state.Terminal {
+			return state.Name
+		}
+	}
+	return w.Initial()
+}
+
+// Fit keeps the status when it is a state of the workflow, otherwise it falls
+// back to the first terminal state for closed todos and to the initial state.
+func (w Workflow) Fit(status string, closed bool) string {
+	if _, ok := w.State(status); ok {
+		return status
+	}
+	if closed {
+		return w.FirstTerminal()
+	}
+	return w.Initial()
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"os"
+	"strconv"
+	"time"
 )
 
 const (
@@ -14,11 +16,48 @@ const (
 	keyPostgresDb       = "POSTGRES_DB"
 	keyDatabaseHost     = "DATABASE_HOST"
 	keySecretKey        = "SECRET_KEY"
+	keyStorage          = "STORAGE"
+	keyDBMaxConnections = "DB_MAX_CONNECTIONS"
+	keyTrashRetention   = "TRASH_RETENTION"
+	keyUndoWindow       = "UNDO_WINDOW"
+	keyAttachmentDir    = "ATTACHMENT_DIR"
+	keyAttachmentSize   = "ATTACHMENT_MAX_SIZE"
+	keyAttachmentQuota  = "ATTACHMENT_QUOTA"
+)
+
+// defaultTrashRetention is how long removed todos stay restorable before they are purged.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// defaultUndoWindow is how long after a change its author may still undo it.
+const defaultUndoWindow = 10 * time.Minute
+
+// defaultAttachmentDir is where uploaded files are kept unless configured otherwise.
+const defaultAttachmentDir = "attachments"
+
+// defaultAttachmentMaxSize is the largest file in bytes a single upload may hold.
+const defaultAttachmentMaxSize = 10 << 20
+
+// defaultAttachmentQuota is how many bytes of attachments an account may store in total.
+const defaultAttachmentQuota = 100 << 20
+
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
 )
 
 type Configuration struct {
-	DB        model.DBConfig
-	SecretKey string
+	DB             model.DBConfig
+	SecretKey      string
+	Storage        string
+	TrashRetention time.Duration
+	UndoWindow     time.Duration
+	Attachments    AttachmentConfig
+}
+
+type AttachmentConfig struct {
+	Dir     string
+	MaxSize int64
+	Quota   int64
 }
 
 var Config Configuration
@@ -33,15 +72,67 @@ func Setup() {
 		postgresDB       = os.Getenv(keyPostgresDb)
 		databaseHost     = os.Getenv(keyDatabaseHost)
 		secretKey        = os.Getenv(keySecretKey)
+		storage          = os.Getenv(keyStorage)
 	)
+	var maxConnections int64
+	if value := os.Getenv(keyDBMaxConnections); len(value) != 0 {
+		var err error
+		if maxConnections, err = strconv.ParseInt(value, 10, 32); err != nil {
+			logger.Fatal("Couldn't parse max db connections", zap.Error(err))
+		}
+	}
+	var trashRetention = defaultTrashRetention
+	if value := os.Getenv(keyTrashRetention); len(value) != 0 {
+		var err error
+		if trashRetention, err = time.ParseDuration(value); err != nil || trashRetention <= 0 {
+			logger.Fatal("Couldn't parse trash retention", zap.String("value", value), zap.Error(err))
+		}
+	}
+	var undoWindow = defaultUndoWindow
+	if value := os.Getenv(keyUndoWindow); len(value) != 0 {
+		var err error
+		if undoWindow, err = time.ParseDuration(value); err != nil || undoWindow <= 0 {
+			logger.Fatal("Couldn't parse undo window", zap.String("value", value), zap.Error(err))
+		}
+	}
+	var attachmentDir = os.Getenv(keyAttachmentDir)
+	if len(attachmentDir) == 0 {
+		attachmentDir = defaultAttachmentDir
+	}
+	var attachmentMaxSize = int64(defaultAttachmentMaxSize)
+	if value := os.Getenv(keyAttachmentSize); len(value) != 0 {
+		var err error
+		if attachmentMaxSize, err = strconv.ParseInt(value, 10, 64); err != nil || attachmentMaxSize <= 0 {
+			logger.Fatal("Couldn't parse attachment max size", zap.String("value", value), zap.Error(err))
+		}
+	}
+	var attachmentQuota = int64(defaultAttachmentQuota)
+	if value := os.Getenv(keyAttachmentQuota); len(value) != 0 {
+		var err error
+		if attachmentQuota, err = strconv.ParseInt(value, 10, 64); err != nil || attachmentQuota <= 0 {
+			logger.Fatal("Couldn't parse attachment quota", zap.String("value", value), zap.Error(err))
+		}
+	}
+	if len(storage) == 0 {
+		storage = StoragePostgres
+	}
 	var dbConfig = model.DBConfig{
-		UserName: postgresUser,
-		Password: postgresPassword,
-		DBName:   postgresDB,
-		DBHost:   databaseHost,
+		UserName:       postgresUser,
+		Password:       postgresPassword,
+		DBName:         postgresDB,
+		DBHost:         databaseHost,
+		MaxConnections: int32(maxConnections),
 	}
 	Config = Configuration{
-		DB:        dbConfig,
-		SecretKey: secretKey,
+		DB:             dbConfig,
+		SecretKey:      secretKey,
+		Storage:        storage,
+		TrashRetention: trashRetention,
+		UndoWindow:     undoWindow,
+		Attachments: AttachmentConfig{
+			Dir:     attachmentDir,
+			MaxSize: attachmentMaxSize,
+			Quota:   attachmentQuota,
+		},
 	}
 }
@@ -92,3 +92,19 @@ func VerifyIsAccessToken(tokenString string) (bool, error) {
 	}
 	return len(claims.UserName) != 0, nil
 }
+
+// AuthenticateToken checks that the token is a valid access token and returns the id of its
+// account. The authentication middleware and the collaboration channel both rely on it.
+func AuthenticateToken(tokenString string) (int, error) {
+	if isValidToken, _ := VerifyToken(tokenString); !isValidToken {
+		return 0, errors.New("token isn't valid")
+	}
+	if isAccessToken, _ := VerifyIsAccessToken(tokenString); !isAccessToken {
+		return 0, errors.New("token isn't an access token")
+	}
+	userId, err := GetUserIdByFromToken(tokenString)
+	if err != nil {
+		return 0, errors.New("access denied")
+	}
+	return userId, nil
+}